@@ -6,12 +6,14 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/coreos/go-oidc"
 	vault "github.com/hashicorp/vault/api"
 	auth "github.com/hashicorp/vault/api/auth/userpass"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 const (
@@ -75,7 +77,8 @@ func KeycloakAuth(address string) {
 	}()
 
 	authURL := oauth2Config.AuthCodeURL("state", oauth2.AccessTypeOffline)
-	fmt.Printf("Open the following URL in your browser to authenticate with Keycloak:\n\n%s\n\n", authURL)
+	// printed to stderr so that the output of commands stays machine-readable
+	fmt.Fprintf(os.Stderr, "Open the following URL in your browser to authenticate with Keycloak:\n\n%s\n\n", authURL)
 
 	<-done
 }
@@ -137,3 +140,18 @@ func GetAdminToken(keycloakUsername, keycloakPassword, keycloakClientID, keycloa
 
 	return token, nil
 }
+
+func GetServiceAccountToken(keycloakClientID, keycloakSecret, keycloakAuthURL string) (*oauth2.Token, error) {
+	cfg := &clientcredentials.Config{
+		ClientID:     keycloakClientID,
+		ClientSecret: keycloakSecret,
+		TokenURL:     keycloakAuthURL,
+	}
+
+	token, err := cfg.Token(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Keycloak service account token: %w", err)
+	}
+
+	return token, nil
+}
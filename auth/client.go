@@ -4,6 +4,8 @@ Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
 package auth
 
 import (
+	"fmt"
+
 	vault "github.com/hashicorp/vault/api"
 )
 
@@ -14,3 +16,33 @@ func Connect(token, address string, client *vault.Client) {
 
 	Client = client
 }
+
+// NewClient returns a Vault client for address that carries no token. It is
+// used for the unauthenticated sys endpoints (init, unseal, seal-status).
+func NewClient(address string) (*vault.Client, error) {
+	config := vault.DefaultConfig()
+	config.Address = address
+	localClient, err := vault.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Vault client: %w", err)
+	}
+	localClient.ClearToken()
+
+	return localClient, nil
+}
+
+// AuthenticateWithToken connects to Vault with an existing token, such as the
+// root token returned by operator init.
+func AuthenticateWithToken(token, address string) error {
+	if token == "" {
+		return fmt.Errorf("a Vault token is required")
+	}
+
+	localClient, err := NewClient(address)
+	if err != nil {
+		return err
+	}
+
+	Connect(token, address, localClient)
+	return nil
+}
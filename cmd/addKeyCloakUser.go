@@ -5,7 +5,6 @@ package cmd
 
 import (
 	"bytes"
-	"cliapp/auth"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -14,19 +13,10 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
-)
-
-const (
-	keycloakAdminURL = "http://localhost:8080/auth/admin/realms/my_realm"
-	keycloakAuthURL  = "http://localhost:8080/auth/realms/master/protocol/openid-connect/token"
-	client_secret    = "admin"
 )
 
 var (
 	keyUser         string
-	adminPassword   string
-	adminUsername   string
 	newUserUsername string
 	newUserPassword string
 )
@@ -38,13 +28,20 @@ var addKeyCloakUserCmd = &cobra.Command{
 	Long: `
 	You can add a user to KeyCloak server using this command. To perform this command you
 	neeed to authenticate yourself as an admin of the keycloak server. You can do this by providing your admin username
-	and password. You also need to provide the username and password of the new user you want to add.
+	and password, or with a confidential client that has a service account in the realm, whose secret is read from
+	a file, the KEYCLOAK_CLIENT_SECRET environment variable or keycloak.json. You also need to provide the username
+	and password of the new user you want to add.
 
 	Example of the addKeyCloakUser command:
 		$ ./cliapp addKeyCloakUser -u=admin -p=password -s=greg -a=gregpass
 
 		$ ./cliapp addKeyCloakUser -u=admin -p=password -s=greg -a=@password.txt
 
+	With a service account:
+		$ ./cliapp addKeyCloakUser --client-id=cliapp-admin --client-secret-file=admin_client_secret.txt -s=greg -a=gregpass
+
+		$ ./cliapp addKeyCloakUser --keycloak-url=http://keycloak:8080/auth --realm=other_realm -s=greg -a=gregpass
+
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		email := newUserUsername + "@gmail.com"
@@ -54,7 +51,7 @@ var addKeyCloakUserCmd = &cobra.Command{
 			Enabled:  true,
 		}
 
-		token, err := auth.GetAdminToken(adminUsername, adminPassword, "admin-cli", client_secret, keycloakAuthURL)
+		token, err := getKeycloakAdminToken()
 		if err != nil {
 			log.Fatalf("Error getting Keycloak token: %v", err)
 		}
@@ -107,14 +104,7 @@ func init() {
 	rootCmd.AddCommand(addKeyCloakUserCmd)
 
 	// ADMIN parameters
-	addKeyCloakUserCmd.Flags().StringVarP(&adminUsername, "adminUsername", "u", "", "Keycloak Admin Username")
-	if err := addKeyCloakUserCmd.MarkFlagRequired("adminUsername"); err != nil {
-		fmt.Println(err)
-	}
-	addKeyCloakUserCmd.Flags().StringVarP(&adminPassword, "adminPassword", "p", "", "Keycloak Admin Password")
-	if err := addKeyCloakUserCmd.MarkFlagRequired("adminPassword"); err != nil {
-		fmt.Println(err)
-	}
+	addKeycloakAdminFlags(addKeyCloakUserCmd, "u", "p")
 
 	// NEW USER parameters
 	addKeyCloakUserCmd.Flags().StringVarP(&newUserUsername, "newUserUsername", "s", "", "New User Username")
@@ -141,11 +131,13 @@ type KeycloakPassword struct {
 }
 
 type KeycloakGroup struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Path      string          `json:"path"`
+	SubGroups []KeycloakGroup `json:"subGroups"`
 }
 
-func createKeycloakUser(token *oauth2.Token, user KeycloakUser) (string, error) {
+func createKeycloakUser(token *keycloakToken, user KeycloakUser) (string, error) {
 	client := &http.Client{}
 
 	userJSON, err := json.Marshal(user)
@@ -153,7 +145,7 @@ func createKeycloakUser(token *oauth2.Token, user KeycloakUser) (string, error)
 		return "", fmt.Errorf("failed to marshal user JSON: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", keycloakAdminURL+"/users", bytes.NewBuffer(userJSON))
+	req, err := http.NewRequest("POST", token.adminURL()+"/users", bytes.NewBuffer(userJSON))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -180,7 +172,7 @@ func createKeycloakUser(token *oauth2.Token, user KeycloakUser) (string, error)
 	return userID, nil
 }
 
-func setKeycloakUserPassword(token *oauth2.Token, userID string, password KeycloakPassword) error {
+func setKeycloakUserPassword(token *keycloakToken, userID string, password KeycloakPassword) error {
 	client := &http.Client{}
 
 	passwordJSON, err := json.Marshal(password)
@@ -188,7 +180,7 @@ func setKeycloakUserPassword(token *oauth2.Token, userID string, password Keyclo
 		return fmt.Errorf("failed to marshal password JSON: %w", err)
 	}
 
-	req, err := http.NewRequest("PUT", keycloakAdminURL+"/users/"+userID+"/reset-password", bytes.NewBuffer(passwordJSON))
+	req, err := http.NewRequest("PUT", token.adminURL()+"/users/"+userID+"/reset-password", bytes.NewBuffer(passwordJSON))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -209,10 +201,10 @@ func setKeycloakUserPassword(token *oauth2.Token, userID string, password Keyclo
 	return nil
 }
 
-func getKeycloakGroupIDByName(token *oauth2.Token, groupName string) (string, error) {
+func getKeycloakGroupIDByName(token *keycloakToken, groupName string) (string, error) {
 	client := &http.Client{}
 
-	req, err := http.NewRequest("GET", keycloakAdminURL+"/groups?search="+groupName, nil)
+	req, err := http.NewRequest("GET", token.adminURL()+"/groups?search="+groupName, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -246,10 +238,10 @@ func getKeycloakGroupIDByName(token *oauth2.Token, groupName string) (string, er
 	return groups[0].ID, nil
 }
 
-func addUserToKeycloakGroup(token *oauth2.Token, userID, groupID string) error {
+func addUserToKeycloakGroup(token *keycloakToken, userID, groupID string) error {
 	client := &http.Client{}
 
-	req, err := http.NewRequest("PUT", keycloakAdminURL+"/users/"+userID+"/groups/"+groupID, nil)
+	req, err := http.NewRequest("PUT", token.adminURL()+"/users/"+userID+"/groups/"+groupID, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -268,3 +260,48 @@ func addUserToKeycloakGroup(token *oauth2.Token, userID, groupID string) error {
 
 	return nil
 }
+
+// getKeycloakGroups returns every group in the realm, with subgroups
+// flattened into the list after their parent.
+func getKeycloakGroups(token *keycloakToken) ([]KeycloakGroup, error) {
+	client := &http.Client{}
+
+	req, err := http.NewRequest("GET", token.adminURL()+"/groups?briefRepresentation=false", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to fetch groups, status: %d, response: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var groups []KeycloakGroup
+	if err := json.Unmarshal(body, &groups); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal groups JSON: %w", err)
+	}
+
+	var flattened []KeycloakGroup
+	var flatten func(groups []KeycloakGroup)
+	flatten = func(groups []KeycloakGroup) {
+		for _, group := range groups {
+			flattened = append(flattened, group)
+			flatten(group.SubGroups)
+		}
+	}
+	flatten(groups)
+
+	return flattened, nil
+}
@@ -0,0 +1,259 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// secretArchiveVersion is the format version of the archives written by
+// export. Import refuses archives with a newer version.
+const secretArchiveVersion = 1
+
+const (
+	ageHeader      = "age-encryption.org/v1"
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+// SecretArchive is the file written by export and read by import. Secret
+// paths are relative to the exported prefix so that the archive can be
+// restored under another mount or prefix.
+type SecretArchive struct {
+	Version    int              `json:"version" yaml:"version"`
+	Mount      string           `json:"mount" yaml:"mount"`
+	Prefix     string           `json:"prefix" yaml:"prefix"`
+	ExportedAt string           `json:"exported_at" yaml:"exported_at"`
+	Secrets    []ArchivedSecret `json:"secrets" yaml:"secrets"`
+}
+
+// ArchivedSecret is one secret of an archive with its versions, oldest
+// first. Only the current version is kept unless all versions were exported.
+type ArchivedSecret struct {
+	Path     string            `json:"path" yaml:"path"`
+	Metadata *ArchivedMetadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Versions []ArchivedVersion `json:"versions" yaml:"versions"`
+}
+
+// ArchivedMetadata holds the settings and custom metadata of a KV version 2
+// secret.
+type ArchivedMetadata struct {
+	MaxVersions        int                    `json:"max_versions" yaml:"max_versions"`
+	CASRequired        bool                   `json:"cas_required" yaml:"cas_required"`
+	DeleteVersionAfter string                 `json:"delete_version_after,omitempty" yaml:"delete_version_after,omitempty"`
+	CustomMetadata     map[string]interface{} `json:"custom_metadata,omitempty" yaml:"custom_metadata,omitempty"`
+}
+
+type ArchivedVersion struct {
+	Version     int                    `json:"version,omitempty" yaml:"version,omitempty"`
+	CreatedTime string                 `json:"created_time,omitempty" yaml:"created_time,omitempty"`
+	Data        map[string]interface{} `json:"data" yaml:"data"`
+}
+
+// archiveFormat returns the encoding of an archive file from its extension,
+// ignoring a final .age: yaml for .yaml and .yml files and json otherwise.
+func archiveFormat(file string) string {
+	if isYAMLFile(strings.TrimSuffix(file, ".age")) {
+		return "yaml"
+	}
+	return "json"
+}
+
+func marshalArchive(archive *SecretArchive, format string) ([]byte, error) {
+	if format == "yaml" {
+		var plain interface{}
+		content, err := json.Marshal(archive)
+		if err != nil {
+			return nil, err
+		}
+		if plain, err = decodeJSON(content); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(yamlNumbers(plain))
+	}
+	return json.MarshalIndent(archive, "", "  ")
+}
+
+// yamlNumbers converts the JSON numbers of Vault's responses to Go numbers,
+// which YAML writes as numbers instead of quoted strings.
+func yamlNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = yamlNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number
+		}
+		if number, err := value.Float64(); err == nil {
+			return number
+		}
+	}
+	return value
+}
+
+// unmarshalArchive decodes a JSON archive, or a YAML one when it is not
+// JSON, since encrypted archives have no telling extension.
+func unmarshalArchive(content []byte) (*SecretArchive, error) {
+	archive := &SecretArchive{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(archive); err != nil {
+		archive = &SecretArchive{}
+		yamlDecoder := yaml.NewDecoder(bytes.NewReader(content))
+		yamlDecoder.KnownFields(true)
+		if yamlErr := yamlDecoder.Decode(archive); yamlErr != nil {
+			return nil, fmt.Errorf("the archive is neither JSON (%v) nor YAML (%v)", err, yamlErr)
+		}
+	}
+	if archive.Mount == "" && len(archive.Secrets) == 0 {
+		return nil, fmt.Errorf("not an export archive, it has no mount or secrets")
+	}
+	if archive.Version < 1 || archive.Version > secretArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+	return archive, nil
+}
+
+// encryptArchive encrypts the content for the age recipients, or for the
+// passphrase when there are none, optionally ASCII armored.
+func encryptArchive(content []byte, recipients []string, passphrase string, armored bool) ([]byte, error) {
+	var targets []age.Recipient
+	for _, recipient := range recipients {
+		parsed, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, parsed)
+	}
+	if len(targets) == 0 {
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, recipient)
+	}
+
+	var buffer bytes.Buffer
+	var out io.Writer = &buffer
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(&buffer)
+		out = armorWriter
+	}
+	writer, err := age.Encrypt(out, targets...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if armorWriter != nil {
+		if err := armorWriter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func isEncryptedArchive(content []byte) bool {
+	trimmed := bytes.TrimLeft(content, " \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte(ageHeader)) || bytes.HasPrefix(trimmed, []byte(ageArmorHeader))
+}
+
+// decryptArchive decrypts the content with the age identities in the
+// identity files, or with the passphrase when there are none.
+func decryptArchive(content []byte, identityFiles []string, passphrase func() (string, error)) ([]byte, error) {
+	var identities []age.Identity
+	for _, file := range identityFiles {
+		identityFile, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := age.ParseIdentities(identityFile)
+		identityFile.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read identities from '%s': %v", file, err)
+		}
+		identities = append(identities, parsed...)
+	}
+	if len(identities) == 0 {
+		secret, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		identity, err := age.NewScryptIdentity(secret)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	var in io.Reader = bytes.NewReader(content)
+	if bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte(ageArmorHeader)) {
+		in = armor.NewReader(bytes.NewReader(bytes.TrimLeft(content, " \t\r\n")))
+	}
+	reader, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the archive: %v", err)
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// readPassphrase reads the passphrase from the file, or asks for it on the
+// terminal, twice when confirm is set.
+func readPassphrase(file string, confirm bool) (string, error) {
+	if file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("unable to read passphrase: %v", err)
+		}
+		passphrase := strings.TrimRight(string(content), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("the passphrase file '%s' is empty", file)
+		}
+		return passphrase, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase (will be hidden): ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("unable to read passphrase: %v", err)
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("the passphrase cannot be empty")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+		again, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("unable to read passphrase: %v", err)
+		}
+		if string(again) != string(passphrase) {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bufio"
+	"cliapp/auth"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// isCASError reports whether Vault refused a write because of its
+// check-and-set version.
+func isCASError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "check-and-set")
+}
+
+// currentVersion returns the current version of a secret, or 0 when it does
+// not exist yet.
+func currentVersion(kv *kvMount, secretPath string) (int, error) {
+	metadata, err := auth.Client.KVv2(kv.Path).GetMetadata(context.Background(), secretPath)
+	if errors.Is(err, vault.ErrSecretNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return metadata.CurrentVersion, nil
+}
+
+// casConflict stops the command with the version that the secret is at.
+func casConflict(kv *kvMount, secretPath string, expected int, err error) {
+	current, readErr := currentVersion(kv, secretPath)
+	switch {
+	case readErr != nil:
+		fmt.Printf("Error: Check-and-set failed for '%s/%s': %v\n", kv.Path, secretPath, err)
+	case expected < 0:
+		fmt.Printf("Error: '%s/%s' requires check-and-set, it is at version %d. Use --cas=%d or --interactive.\n",
+			kv.Path, secretPath, current, current)
+	default:
+		fmt.Printf("Error: '%s/%s' was changed by someone else: it is at version %d, but the write expected version %d.\n",
+			kv.Path, secretPath, current, expected)
+	}
+	os.Exit(1)
+}
+
+// casOptions returns the check-and-set option for a version, none when the
+// version is negative.
+func casOptions(cas int) []vault.KVOption {
+	if cas < 0 {
+		return nil
+	}
+	return []vault.KVOption{vault.WithCheckAndSet(cas)}
+}
+
+// readModifyWrite reads the current version of a secret, applies changes to
+// it and writes it back with check-and-set against the version read. With
+// replace the changes become the whole secret instead of being merged into
+// it. When someone else writes in between, the user is shown which keys
+// they changed and asked whether to merge, overwrite or abort.
+func readModifyWrite(kv *kvMount, secretPath string, changes map[string]interface{}, replace bool, retries int) (*vault.KVSecret, error) {
+	base, version, err := readForUpdate(kv, secretPath)
+	if err != nil {
+		return nil, err
+	}
+	data := applyChanges(base, changes, replace)
+
+	for attempt := 0; ; attempt++ {
+		written, err := auth.Client.KVv2(kv.Path).Put(context.Background(), secretPath, data, vault.WithCheckAndSet(version))
+		if !isCASError(err) {
+			return written, err
+		}
+		if attempt >= retries {
+			return nil, fmt.Errorf("'%s/%s' kept changing, gave up after %d retries", kv.Path, secretPath, retries)
+		}
+
+		current, latest, err := readForUpdate(kv, secretPath)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Conflict: '%s/%s' is at version %d, your changes are based on version %d.\n",
+			kv.Path, secretPath, latest, version)
+		for _, line := range changedKeys(base, current) {
+			fmt.Fprintln(os.Stderr, "  "+line)
+		}
+
+		switch askMerge() {
+		case "m":
+			data = applyChanges(current, changes, false)
+		case "o":
+			// keep data as it is, only the version it replaces changes
+		default:
+			return nil, fmt.Errorf("write aborted, '%s/%s' was not changed", kv.Path, secretPath)
+		}
+		base, version = current, latest
+	}
+}
+
+// readForUpdate returns the data and version of a secret, an empty secret at
+// version 0 when it does not exist.
+func readForUpdate(kv *kvMount, secretPath string) (map[string]interface{}, int, error) {
+	secret, err := auth.Client.KVv2(kv.Path).Get(context.Background(), secretPath)
+	if errors.Is(err, vault.ErrSecretNotFound) {
+		return map[string]interface{}{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read secret: %v", err)
+	}
+	data := secret.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, secret.VersionMetadata.Version, nil
+}
+
+// applyChanges merges the changes into base as a JSON merge patch, or with
+// replace returns the changes as the new data.
+func applyChanges(base, changes map[string]interface{}, replace bool) map[string]interface{} {
+	if replace {
+		return changes
+	}
+	return mergePatch(base, changes)
+}
+
+// changedKeys lists the keys added, removed or changed between two versions
+// of a secret, without their values.
+func changedKeys(before, after map[string]interface{}) []string {
+	var lines []string
+	for _, change := range diffSecrets(before, after) {
+		lines = append(lines, change.Change+": "+change.Key)
+	}
+	return lines
+}
+
+func askMerge() string {
+	fmt.Fprint(os.Stderr, "[m]erge your keys into the new version, [o]verwrite it with your version, or [a]bort? ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" {
+		return "a"
+	}
+	return answer[:1]
+}
@@ -0,0 +1,360 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	pathpkg "path"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	copyRecursive  bool
+	copyVersions   bool
+	copyMetadata   bool
+	copyDryRun     bool
+	copyForce      bool
+	copyToInstance bool
+	u24            string
+	p24            string
+)
+
+// copyItem is a secret to copy, with paths relative to the mounts.
+type copyItem struct {
+	Source      string
+	Destination string
+	Exists      bool
+}
+
+// cpCmd represents the cp command
+var cpCmd = &cobra.Command{
+	Use:   "cp <mount/path> <mount/path>",
+	Short: "Copy secrets between paths, mounts and instances",
+	Long: `
+	Copies a secret, or with -R every secret under a folder, to another path. The destination
+	can be on another mount, and with --to-instance on the other Vault instance, which needs
+	userpass authentication. A destination ending with "/" is a folder to copy into. By
+	default the current version is copied; --versions copies every version that is not
+	deleted or destroyed, oldest first, and --metadata copies the custom metadata and the
+	max versions, check-and-set and delete version after settings. Existing destination
+	secrets are only overwritten with --force, and --dry-run lists what would be copied.
+
+	Examples of the cp command(Keycloak Authentication):
+		$ ./cliapp cp secret/my-secret secret/my-secret-copy
+
+		$ ./cliapp cp secret/team kv/team -R --versions --metadata
+
+		$ ./cliapp cp secret/team kv/ -R --dry-run
+
+	To use Userpass Authentication:
+		$ ./cliapp cp secret/my-secret kv/my-secret --user=username --pass=password
+
+	To copy to the other instance:
+		$ ./cliapp cp secret/team secret/team -R --to-instance --user=username --pass=password
+	`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		copySecrets(cmd, args, u24, p24, false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+
+	addCopyFlags(cpCmd)
+
+	// userpass
+	cpCmd.Flags().StringVarP(&u24, "user", "u", "", "Userpass username")
+	cpCmd.Flags().StringVarP(&p24, "pass", "a", "", "Userpass password")
+	cpCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	cpCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// addCopyFlags registers the flags shared by cp and mv.
+func addCopyFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&copyRecursive, "recursive", "R", false, "Copy every secret under the folder")
+	cmd.Flags().BoolVar(&copyVersions, "versions", false, "Copy every version that is not deleted or destroyed")
+	cmd.Flags().BoolVar(&copyMetadata, "metadata", false, "Copy the custom metadata and settings")
+	cmd.Flags().BoolVarP(&copyDryRun, "dry-run", "d", false, "Only list what would be copied")
+	cmd.Flags().BoolVarP(&copyForce, "force", "f", false, "Overwrite existing destination secrets")
+	cmd.Flags().BoolVar(&copyToInstance, "to-instance", false, "Write to the other Vault instance, needs --user and --pass")
+}
+
+// copySecrets copies the secrets given by the arguments, deleting each
+// source secret after it is copied when move is set.
+func copySecrets(cmd *cobra.Command, args []string, user, pass string, move bool) {
+	verb := "Copy"
+	if move {
+		verb = "Move"
+	}
+
+	login(cmd, user, pass)
+	source := auth.Client
+	destination := source
+	if copyToInstance {
+		if !cmd.Flag("user").Changed || !cmd.Flag("pass").Changed {
+			fmt.Println("Error: You must provide a username and password to use the other instance.")
+			os.Exit(1)
+		}
+		if err := auth.AuthenticateWithUserPass(user, pass, util.UpdateAddress(!instance)); err != nil {
+			log.Fatalf("%v", err)
+		}
+		destination = auth.Client
+	}
+
+	auth.Client = source
+	sourceKV, sourcePath, err := lookupKVMount(args[0])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	auth.Client = destination
+	destinationKV, destinationPath, err := lookupKVMount(args[1])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if move && !moveLatestOnly && sourceKV.Version == 2 && destinationKV.Version == 2 {
+		// the source is deleted with its history, so a move keeps it
+		copyVersions, copyMetadata = true, true
+	}
+	if copyVersions {
+		requireKVv2(sourceKV, "Copying versions")
+		requireKVv2(destinationKV, "Copying versions")
+	}
+	if copyMetadata {
+		requireKVv2(sourceKV, "Copying metadata")
+		requireKVv2(destinationKV, "Copying metadata")
+	}
+
+	auth.Client = source
+	items := copyItems(sourceKV, sourcePath, destinationPath, strings.HasSuffix(args[1], "/"))
+	sameMount := source == destination && sourceKV.Path == destinationKV.Path
+	if move && !moveLatestOnly && !copyVersions && sourceKV.Version == 2 {
+		for _, item := range items {
+			count, err := liveVersions(sourceKV, item.Source)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if count > 1 {
+				fmt.Printf("Error: '%s/%s' has %d versions and only the current one would be moved, use --latest-only to drop the others\n", sourceKV.Path, item.Source, count)
+				os.Exit(1)
+			}
+		}
+	}
+
+	auth.Client = destination
+	var existing int
+	for i, item := range items {
+		if sameMount && item.Source == item.Destination {
+			fmt.Printf("Error: '%s/%s' would be copied onto itself\n", sourceKV.Path, item.Source)
+			os.Exit(1)
+		}
+		_, err := readSecret(destinationKV, item.Destination)
+		if err != nil && !errors.Is(err, vault.ErrSecretNotFound) {
+			log.Fatalf("unable to read '%s/%s': %v", destinationKV.Path, item.Destination, err)
+		}
+		if err == nil {
+			items[i].Exists = true
+			existing++
+		}
+	}
+
+	if copyDryRun || (existing > 0 && !copyForce) {
+		for _, item := range items {
+			note := ""
+			if item.Exists {
+				note = " (exists)"
+			}
+			fmt.Printf("%s %s/%s -> %s/%s%s\n", verb, sourceKV.Path, item.Source, destinationKV.Path, item.Destination, note)
+		}
+		if existing > 0 && !copyForce {
+			fmt.Printf("Error: %d destination secrets exist, use --force to overwrite them\n", existing)
+			os.Exit(1)
+		}
+		fmt.Println("Dry run, nothing was changed.")
+		return
+	}
+
+	var copied, versions, skipped int
+	for _, item := range items {
+		written, skippedVersions, err := copySecret(sourceKV, destinationKV, item, source, destination)
+		if err != nil {
+			fmt.Printf("Error: %s of '%s/%s' failed after %d secrets: %v\n", verb, sourceKV.Path, item.Source, copied, err)
+			os.Exit(1)
+		}
+		if move {
+			auth.Client = source
+			if err := removeSecret(sourceKV, item.Source); err != nil {
+				fmt.Printf("Error: '%s/%s' was copied but not deleted: %v\n", sourceKV.Path, item.Source, err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("%s/%s -> %s/%s\n", sourceKV.Path, item.Source, destinationKV.Path, item.Destination)
+		copied++
+		versions += written
+		skipped += skippedVersions
+	}
+
+	past := "Copied"
+	if move {
+		past = "Moved"
+	}
+	fmt.Printf("%s %d secrets (%d versions).\n", past, copied, versions)
+	if skipped > 0 {
+		fmt.Printf("Skipped %d deleted or destroyed versions.\n", skipped)
+	}
+}
+
+// copyItems lists the secrets to copy and their destinations. Without -R
+// the source is a single secret, which is copied into the destination when
+// it is a folder.
+func copyItems(sourceKV *kvMount, sourcePath, destinationPath string, intoFolder bool) []copyItem {
+	if !copyRecursive {
+		if sourcePath == "" {
+			fmt.Println("Error: The source is a mount, use -R to copy all of its secrets")
+			os.Exit(1)
+		}
+		if intoFolder || destinationPath == "" {
+			destinationPath = joinSecretPath(destinationPath, pathpkg.Base(sourcePath))
+		}
+		return []copyItem{{Source: sourcePath, Destination: destinationPath}}
+	}
+
+	entries, err := walkSecrets(sourceKV, sourcePath, 0, 8)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	prefix := ""
+	if sourcePath != "" {
+		prefix = sourcePath + "/"
+	}
+	var items []copyItem
+	for _, entry := range entries {
+		if entry.Dir {
+			continue
+		}
+		items = append(items, copyItem{
+			Source:      entry.Path,
+			Destination: joinSecretPath(destinationPath, strings.TrimPrefix(entry.Path, prefix)),
+		})
+	}
+	if len(items) == 0 {
+		fmt.Printf("Error: No secrets under '%s/%s'\n", sourceKV.Path, sourcePath)
+		os.Exit(1)
+	}
+	return items
+}
+
+func joinSecretPath(folder, name string) string {
+	if folder == "" {
+		return name
+	}
+	return strings.TrimSuffix(folder, "/") + "/" + name
+}
+
+// copySecret copies one secret and returns the number of versions written
+// and skipped.
+func copySecret(sourceKV, destinationKV *kvMount, item copyItem, source, destination *vault.Client) (int, int, error) {
+	auth.Client = source
+	archived, skipped, err := archiveSecret(sourceKV, item.Source, item.Source, copyVersions)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(archived.Versions) == 0 {
+		return 0, skipped, fmt.Errorf("every version is deleted or destroyed")
+	}
+
+	auth.Client = destination
+	written, err := writeVersions(destinationKV, item.Destination, archived.Versions)
+	if err != nil {
+		return 0, 0, err
+	}
+	if archived.Metadata != nil && destinationKV.Version == 2 {
+		if err := copySecretMetadata(destinationKV, item.Destination, archived.Metadata); err != nil {
+			return 0, 0, err
+		}
+	}
+	return written, skipped, nil
+}
+
+// writeVersions writes the versions as new versions of the secret, oldest
+// first, with check-and-set so that a concurrent change is not lost. Only
+// the last version is written to a KV version 1 mount.
+func writeVersions(kv *kvMount, secretPath string, versions []ArchivedVersion) (int, error) {
+	if kv.Version != 2 {
+		return 1, writeSecret(kv, secretPath, versions[len(versions)-1].Data)
+	}
+	_, cas, err := readForUpdate(kv, secretPath)
+	if err != nil {
+		return 0, err
+	}
+	for i, version := range versions {
+		_, err := auth.Client.KVv2(kv.Path).Put(context.Background(), secretPath, version.Data, vault.WithCheckAndSet(cas))
+		if isCASError(err) {
+			return i, fmt.Errorf("'%s/%s' was changed while writing its versions", kv.Path, secretPath)
+		}
+		if err != nil {
+			return i, fmt.Errorf("unable to write secret: %v", err)
+		}
+		cas++
+	}
+	return len(versions), nil
+}
+
+// copySecretMetadata writes the metadata settings and custom metadata with
+// --metadata. Otherwise only the file markers are copied, which are needed
+// to restore file values.
+func copySecretMetadata(kv *kvMount, secretPath string, metadata *ArchivedMetadata) error {
+	if copyMetadata {
+		return putArchivedMetadata(kv, secretPath, metadata)
+	}
+	markers := map[string]interface{}{}
+	for key, value := range metadata.CustomMetadata {
+		if strings.HasPrefix(key, fileMarkerPrefix) {
+			markers[key] = value
+		}
+	}
+	if len(markers) == 0 {
+		return nil
+	}
+	err := auth.Client.KVv2(kv.Path).PatchMetadata(context.Background(), secretPath, vault.KVMetadataPatchInput{CustomMetadata: markers})
+	if err != nil {
+		return fmt.Errorf("unable to write metadata: %v", err)
+	}
+	return nil
+}
+
+// liveVersions counts the versions of a secret that are not deleted or
+// destroyed.
+func liveVersions(kv *kvMount, secretPath string) (int, error) {
+	metadata, err := auth.Client.KVv2(kv.Path).GetMetadata(context.Background(), secretPath)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the metadata of '%s/%s': %v", kv.Path, secretPath, err)
+	}
+	count := 0
+	for _, version := range metadata.Versions {
+		if !version.Destroyed && version.DeletionTime.IsZero() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// removeSecret deletes a secret for good: every version and the metadata
+// on version 2 mounts.
+func removeSecret(kv *kvMount, secretPath string) error {
+	if kv.Version == 2 {
+		return auth.Client.KVv2(kv.Path).DeleteMetadata(context.Background(), secretPath)
+	}
+	return auth.Client.KVv1(kv.Path).Delete(context.Background(), secretPath)
+}
@@ -28,7 +28,7 @@ var versions []int
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
-	Use:   "delete",
+	Use:   "delete [mount/path]",
 	Short: "Deletes the data for the provided version",
 	Long: `
 	Deletes the data for the provided version and path in the key-value store. The
@@ -39,18 +39,22 @@ var deleteCmd = &cobra.Command{
 	the secret are needed.
 
 	Examples of the delete command(Keycloak Authentication):
-		$ ./cliapp delete --mount=secret --path=secret/my-secret
+		$ ./cliapp delete secret/my-secret
 
-		$ ./cliapp delete --mount=secret --path=secret/my-secret --version=2
+		$ ./cliapp delete secret/my-secret --version=2
 
-		$ ./cliapp delete --mount=secret --path=secret/my-secret --version=2,3,4
+		$ ./cliapp delete secret/my-secret --version=2,3,4
+
+	The mount is found from the mount table. The mount and the path inside it can also be given with flags:
+		$ ./cliapp delete --mount=secret --path=my-secret
 
 	To use Userpass Authentication:
-		$ ./cliapp delete --mount=secret --path=secret/my-secret --user=username --pass=password
+		$ ./cliapp delete secret/my-secret --user=username --pass=password
 
 	To use a different instance:
-		$ ./cliapp delete --mount=secret --path=secret/my-secret --user=username --pass=password --instance
+		$ ./cliapp delete secret/my-secret --user=username --pass=password --instance
 	`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
 			address := util.UpdateAddress(instance)
@@ -64,9 +68,11 @@ var deleteCmd = &cobra.Command{
 			auth.KeycloakAuth(address)
 		}
 
-		check()
-		util.ValidatePath(delMount)
-		util.ValidatePath(delPath)
+		kv := resolveKVArgs(args, &delMount, &delPath, true)
+		check(kv)
+		if delVersion != "" {
+			requireKVv2(kv, "Deleting a version")
+		}
 
 		if strings.Contains(delVersion, ",") { // multiple versions
 			vers := strings.Split(delVersion, ",")
@@ -96,7 +102,7 @@ var deleteCmd = &cobra.Command{
 				log.Fatalf("Secret not Deleted error: %d ", err)
 			}
 		} else { //current version
-			err := auth.Client.KVv2(delMount).Delete(context.Background(), delPath)
+			err := deleteSecret(kv, delPath)
 			if err != nil {
 				log.Fatalf("Secret not Deleted error: %d ", err)
 			}
@@ -112,17 +118,9 @@ func init() {
 	//mount
 	deleteCmd.Flags().StringVarP(&delMount, "mount", "m", "", "The mount path to retrive secrets from")
 
-	if err := deleteCmd.MarkFlagRequired("mount"); err != nil {
-		fmt.Println(err)
-	}
-
 	// path
 	deleteCmd.Flags().StringVarP(&delPath, "path", "p", "", "path to the secret")
 
-	if err := deleteCmd.MarkFlagRequired("path"); err != nil {
-		fmt.Println(err)
-	}
-
 	// version
 	deleteCmd.Flags().StringVarP(&delVersion, "version", "v", "", "version of secret")
 
@@ -135,9 +133,9 @@ func init() {
 
 }
 
-func check() {
-	_, error := auth.Client.KVv2(delMount).Get(context.Background(), delPath)
+func check(kv *kvMount) {
+	_, error := readSecret(kv, delPath)
 	if error != nil {
-		log.Fatalf("Unable to read path: %s", path)
+		log.Fatalf("Unable to read path: %s", delPath)
 	}
 }
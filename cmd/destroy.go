@@ -28,7 +28,7 @@ var desVersions []int
 
 // destroyCmd represents the destroy command
 var destroyCmd = &cobra.Command{
-	Use:   "destroy",
+	Use:   "destroy [mount/path]",
 	Short: "Permanently removes the specified versions' data from the key-value store",
 	Long: `
 	Permanently removes the specified versions' data from the key-value store. This
@@ -36,16 +36,20 @@ var destroyCmd = &cobra.Command{
 	A Version must be provided or multiple versions to destroy a secret at the path.
 
 	Example of the destroy command(Keycloak Authentication):
-		$ ./cliapp destroy --mount=test --path=path --version=1
+		$ ./cliapp destroy test/path --version=1
+
+		$ ./cliapp destroy test/path --version=1,2,3
 
-		$ ./cliapp destroy --mount=test --path=path --version=1,2,3
+	The mount is found from the mount table. The mount and the path inside it can also be given with flags:
+		$ ./cliapp destroy --mount=test --path=path --version=1
 
 	To use Userpass Authentication:
-		$ ./cliapp destroy --mount=test --path=path --version=1 --user=username --pass=password
+		$ ./cliapp destroy test/path --version=1 --user=username --pass=password
 
 	To use a different instance:
-		$ ./cliapp destroy --mount=test --path=path --version=1 --user=username --pass=password --instance
+		$ ./cliapp destroy test/path --version=1 --user=username --pass=password --instance
 	`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
 			address := util.UpdateAddress(instance)
@@ -59,8 +63,8 @@ var destroyCmd = &cobra.Command{
 			auth.KeycloakAuth(address)
 		}
 
-		util.ValidatePath(desMount)
-		util.ValidatePath(desPath)
+		kv := resolveKVArgs(args, &desMount, &desPath, true)
+		requireKVv2(kv, "destroy")
 
 		if strings.Contains(desVersion, ",") { // multiple versions
 			vers := strings.Split(desVersion, ",")
@@ -100,17 +104,9 @@ func init() {
 	//mount
 	destroyCmd.Flags().StringVarP(&desMount, "mount", "m", "", "The mount path to destroy secrets")
 
-	if err := destroyCmd.MarkFlagRequired("mount"); err != nil {
-		fmt.Println(err)
-	}
-
 	// path
 	destroyCmd.Flags().StringVarP(&desPath, "path", "p", "", "path to the secret")
 
-	if err := destroyCmd.MarkFlagRequired("path"); err != nil {
-		fmt.Println(err)
-	}
-
 	// version
 	destroyCmd.Flags().StringVarP(&desVersion, "version", "v", "", "version of secret")
 
@@ -0,0 +1,192 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffMount  string
+	diffPath   string
+	diffFrom   int
+	diffTo     int
+	diffReveal bool
+	u21        string
+	p21        string
+)
+
+const maskedValue = "********"
+
+// keyChange is a key that differs between two versions of a secret.
+type keyChange struct {
+	Key    string      `json:"key"`
+	Change string      `json:"change"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [mount/path]",
+	Short: "Compare two versions of a secret",
+	Long: `
+	Compares two versions of a secret on a KV version 2 mount, showing the keys that were
+	added (+), removed (-) or changed (~) from the first version to the second. The second
+	version is the current one unless --to is given. Values are masked unless --reveal is
+	given.
+
+	Examples of the diff command(Keycloak Authentication):
+		$ ./cliapp diff secret/my-secret --from=2
+
+		$ ./cliapp diff secret/my-secret --from=2 --to=4
+
+		$ ./cliapp diff secret/my-secret --from=2 --reveal
+
+	To use Userpass Authentication:
+		$ ./cliapp diff secret/my-secret --from=2 --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp diff secret/my-secret --from=2 --user=username --pass=password --instance
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if diffFrom < 1 || diffTo < 0 {
+			fmt.Println("Error: Versions of secret cannot be less than one")
+			os.Exit(1)
+		}
+
+		login(cmd, u21, p21)
+		kv := resolveKVArgs(args, &diffMount, &diffPath, true)
+		requireKVv2(kv, "Diff")
+
+		from, _, err := readVersion(kv, diffPath, diffFrom)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		to, toVersion, err := readVersion(kv, diffPath, diffTo)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "Comparing version %d with version %d of '%s/%s'\n", diffFrom, toVersion, kv.Path, diffPath)
+		printDiff(diffSecrets(from, to), diffReveal)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffMount, "mount", "m", "", "The mount path of the secret")
+	diffCmd.Flags().StringVarP(&diffPath, "path", "p", "", "path to the secret")
+
+	// versions
+	diffCmd.Flags().IntVarP(&diffFrom, "from", "f", 0, "version to compare from")
+	diffCmd.Flags().IntVarP(&diffTo, "to", "t", 0, "version to compare to, the current version by default")
+	diffCmd.Flags().BoolVarP(&diffReveal, "reveal", "r", false, "Show the values instead of masking them")
+
+	if err := diffCmd.MarkFlagRequired("from"); err != nil {
+		fmt.Println(err)
+	}
+
+	// userpass
+	diffCmd.Flags().StringVarP(&u21, "user", "u", "", "Userpass username")
+	diffCmd.Flags().StringVarP(&p21, "pass", "a", "", "Userpass password")
+	diffCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	diffCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// readVersion reads the data of a version of a secret, the current version
+// when version is 0. It returns the version read, and an error when that
+// version is deleted or destroyed.
+func readVersion(kv *kvMount, secretPath string, version int) (map[string]interface{}, int, error) {
+	var err error
+	var secret *vault.KVSecret
+	if version == 0 {
+		secret, err = auth.Client.KVv2(kv.Path).Get(context.Background(), secretPath)
+	} else {
+		secret, err = auth.Client.KVv2(kv.Path).GetVersion(context.Background(), secretPath, version)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read secret: %v", err)
+	}
+
+	metadata := secret.VersionMetadata
+	if metadata == nil {
+		return nil, 0, fmt.Errorf("no version metadata for '%s/%s'", kv.Path, secretPath)
+	}
+	if metadata.Destroyed {
+		return nil, metadata.Version, fmt.Errorf("version %d of '%s/%s' is destroyed", metadata.Version, kv.Path, secretPath)
+	}
+	if secret.Data == nil {
+		return nil, metadata.Version, fmt.Errorf("version %d of '%s/%s' is deleted, undelete it first", metadata.Version, kv.Path, secretPath)
+	}
+	return secret.Data, metadata.Version, nil
+}
+
+// diffSecrets returns the keys added, removed or changed from before to
+// after, sorted by key.
+func diffSecrets(before, after map[string]interface{}) []keyChange {
+	var changes []keyChange
+	for key, value := range after {
+		old, ok := before[key]
+		if !ok {
+			changes = append(changes, keyChange{Key: key, Change: "added", New: value})
+		} else if compactJSON(old) != compactJSON(value) {
+			changes = append(changes, keyChange{Key: key, Change: "changed", Old: old, New: value})
+		}
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, keyChange{Key: key, Change: "removed", Old: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// printDiff prints the changes in the selected format, with the values
+// masked unless reveal is set.
+func printDiff(changes []keyChange, reveal bool) {
+	if !reveal {
+		masked := make([]keyChange, len(changes))
+		for i, change := range changes {
+			masked[i] = keyChange{Key: change.Key, Change: change.Change}
+			if change.Old != nil {
+				masked[i].Old = maskedValue
+			}
+			if change.New != nil {
+				masked[i].New = maskedValue
+			}
+		}
+		changes = masked
+	}
+
+	printFormatted(changes, func() {
+		if len(changes) == 0 {
+			fmt.Println("No differences.")
+			return
+		}
+		for _, change := range changes {
+			switch change.Change {
+			case "added":
+				fmt.Printf("+ %s: %s\n", change.Key, fieldText(change.New))
+			case "removed":
+				fmt.Printf("- %s: %s\n", change.Key, fieldText(change.Old))
+			default:
+				fmt.Printf("~ %s: %s -> %s\n", change.Key, fieldText(change.Old), fieldText(change.New))
+			}
+		}
+	})
+}
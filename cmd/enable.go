@@ -8,25 +8,29 @@ import (
 	"cliapp/util"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	enablePath string
-	u7         string
-	p7         string
+	enablePath    string
+	enableVersion int
+	u7            string
+	p7            string
 )
 
 // enableCmd represents the enable command
 var enableCmd = &cobra.Command{
 	Use:   "enable",
 	Short: "Enable a kv engine v2 on path",
-	Long: `Enable a kv engine v2 on path
+	Long: `Enable a kv engine v2 on path, or a kv engine v1 with --version=1
 
 	Example of the enable command(Keycloak Authentication):
 		$ ./cliapp enable --path=kv
+
+		$ ./cliapp enable --path=legacy --version=1
 	
 	To use Userpass Authentication:
 		$ ./cliapp enable --path=kv --user=username --pass=password
@@ -47,10 +51,15 @@ var enableCmd = &cobra.Command{
 			auth.KeycloakAuth(address)
 		}
 
+		if enableVersion != 1 && enableVersion != 2 {
+			fmt.Println("Error: The KV version must be 1 or 2")
+			os.Exit(1)
+		}
+
 		_, err := auth.Client.Logical().Write("sys/mounts/"+enablePath, map[string]interface{}{
 			"type": "kv",
 			"options": map[string]interface{}{
-				"version": "2",
+				"version": strconv.Itoa(enableVersion),
 			},
 		})
 		if err != nil {
@@ -73,6 +82,15 @@ var enableCmd = &cobra.Command{
 				fmt.Println(err)
 				return
 			}
+			if enableVersion == 1 { // kv v1 has no versioned paths
+				UpdatePolicy(policy, existingPolicy+`
+				# New rules for path
+				path "`+enablePath+`/*" {
+					capabilities = ["create", "read", "update", "delete", "list"]
+				}
+				`)
+				continue
+			}
 			// update the policy
 			// if policy contains 'admin' in the string then write the policy with the new rules
 			if !strings.Contains(policy, "admin") { // if policy is not admin
@@ -96,7 +114,7 @@ var enableCmd = &cobra.Command{
 				}
 
 				path "` + enablePath + `/metadata/*" {
-				capabilities = ["list", "read", "delete"]
+				capabilities = ["create", "update", "patch", "list", "read", "delete"]
 				}
 
 				path "` + enablePath + `/undelete/*" {
@@ -123,6 +141,9 @@ func init() {
 		fmt.Println(err)
 	}
 
+	// version
+	enableCmd.Flags().IntVarP(&enableVersion, "version", "v", 2, "version of the kv engine, 1 or 2")
+
 	// userpass
 	enableCmd.Flags().StringVarP(&u7, "user", "u", "", "Userpass username")
 	enableCmd.Flags().StringVarP(&p7, "pass", "a", "", "Userpass password")
@@ -0,0 +1,253 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kvExportFile           string
+	kvExportAllVersions    bool
+	kvExportRecipients     []string
+	kvExportPassphrase     bool
+	kvExportPassphraseFile string
+	kvExportArmor          bool
+	kvExportPlaintext      bool
+	u26                    string
+	p26                    string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <mount/prefix>",
+	Short: "Back up the secrets under a mount or prefix to an encrypted archive",
+	Long: `
+	Walks a mount, or a folder of it, and writes every secret to an archive: the data of the
+	current version, or of every version that is not deleted or destroyed with
+	--all-versions, and on KV version 2 mounts the custom metadata and the max versions,
+	check-and-set and delete version after settings. The archive is JSON, or YAML when the
+	file ends with .yaml or .yml (optionally followed by .age), and is encrypted with age
+	for one or more age recipients or for a passphrase. An unencrypted archive holds the
+	secrets in plain text and is only written with --plaintext. The archive is restored
+	with import. Reading the metadata needs the admin policy.
+
+	Examples of the export command(Keycloak Authentication):
+		$ ./cliapp export secret --file=secret.json.age --passphrase
+
+		$ ./cliapp export secret/team --file=team.yaml.age --all-versions --recipient=age1...
+
+		$ ./cliapp export secret/team --file=team.json.age --passphrase-file=backup.pass --armor
+
+	To use Userpass Authentication:
+		$ ./cliapp export secret --file=secret.json.age --passphrase --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp export secret --file=secret.json.age --passphrase --user=username --pass=password --instance
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		modes := 0
+		if len(kvExportRecipients) > 0 {
+			modes++
+		}
+		if kvExportPassphrase || kvExportPassphraseFile != "" {
+			modes++
+		}
+		if kvExportPlaintext {
+			modes++
+		}
+		if modes != 1 {
+			fmt.Println("Error: Give either --recipient, --passphrase or --passphrase-file to encrypt the archive, or --plaintext")
+			os.Exit(1)
+		}
+		if kvExportPlaintext && kvExportArmor {
+			fmt.Println("Error: --armor only applies to encrypted archives")
+			os.Exit(1)
+		}
+
+		var passphrase string
+		if kvExportPassphrase || kvExportPassphraseFile != "" {
+			var err error
+			if passphrase, err = readPassphrase(kvExportPassphraseFile, true); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		login(cmd, u26, p26)
+		kv, prefix, err := lookupKVMount(args[0])
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if kvExportAllVersions {
+			requireKVv2(kv, "Exporting all versions")
+		}
+
+		entries, err := walkSecrets(kv, prefix, 0, 8)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		var secrets []string
+		for _, entry := range entries {
+			if !entry.Dir {
+				secrets = append(secrets, entry.Path)
+			}
+		}
+		if len(secrets) == 0 {
+			fmt.Printf("Error: No secrets under '%s/%s'\n", kv.Path, prefix)
+			os.Exit(1)
+		}
+
+		archive := &SecretArchive{
+			Version:    secretArchiveVersion,
+			Mount:      kv.Path,
+			Prefix:     prefix,
+			ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		var versions, skipped int
+		for i, secretPath := range secrets {
+			relative := strings.TrimPrefix(secretPath, prefix+"/")
+			if prefix == "" {
+				relative = secretPath
+			}
+			archived, skippedVersions, err := archiveSecret(kv, secretPath, relative, kvExportAllVersions)
+			if err != nil {
+				log.Fatalf("unable to export '%s/%s': %v", kv.Path, secretPath, err)
+			}
+			skipped += skippedVersions
+			if len(archived.Versions) == 0 {
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s: skipped, every version is deleted or destroyed\n", i+1, len(secrets), secretPath)
+				continue
+			}
+			archive.Secrets = append(archive.Secrets, archived)
+			versions += len(archived.Versions)
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s (%d versions)\n", i+1, len(secrets), secretPath, len(archived.Versions))
+		}
+
+		content, err := marshalArchive(archive, archiveFormat(kvExportFile))
+		if err != nil {
+			log.Fatalf("unable to encode archive: %v", err)
+		}
+		if !kvExportPlaintext {
+			if content, err = encryptArchive(content, kvExportRecipients, passphrase, kvExportArmor); err != nil {
+				log.Fatalf("unable to encrypt archive: %v", err)
+			}
+		}
+		if err := ioutil.WriteFile(kvExportFile, content, 0600); err != nil {
+			log.Fatalf("unable to write archive: %v", err)
+		}
+
+		fmt.Printf("Exported %d secrets (%d versions) from '%s/%s' to: %s\n", len(archive.Secrets), versions, kv.Path, prefix, kvExportFile)
+		if skipped > 0 {
+			fmt.Printf("Skipped %d deleted or destroyed versions.\n", skipped)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&kvExportFile, "file", "f", "", "Archive to write, .json or .yaml, optionally followed by .age")
+	if err := exportCmd.MarkFlagRequired("file"); err != nil {
+		fmt.Println(err)
+	}
+	exportCmd.Flags().BoolVar(&kvExportAllVersions, "all-versions", false, "Export every version that is not deleted or destroyed")
+
+	// encryption
+	exportCmd.Flags().StringArrayVarP(&kvExportRecipients, "recipient", "r", nil, "age recipient to encrypt the archive for, can be repeated")
+	exportCmd.Flags().BoolVar(&kvExportPassphrase, "passphrase", false, "Encrypt the archive with a passphrase read from the terminal")
+	exportCmd.Flags().StringVar(&kvExportPassphraseFile, "passphrase-file", "", "Encrypt the archive with the passphrase in this file")
+	exportCmd.Flags().BoolVar(&kvExportArmor, "armor", false, "Write the encrypted archive as ASCII armored text")
+	exportCmd.Flags().BoolVar(&kvExportPlaintext, "plaintext", false, "Write the archive without encryption")
+
+	// userpass
+	exportCmd.Flags().StringVarP(&u26, "user", "u", "", "Userpass username")
+	exportCmd.Flags().StringVarP(&p26, "pass", "a", "", "Userpass password")
+	exportCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	exportCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// archiveSecret reads a secret into its archived form under the relative
+// path: the current version, or every version that is not deleted or
+// destroyed, and the metadata on KV version 2 mounts. It also returns the
+// number of versions skipped.
+func archiveSecret(kv *kvMount, secretPath, relative string, allVersions bool) (ArchivedSecret, int, error) {
+	archived := ArchivedSecret{Path: relative}
+	if kv.Version != 2 {
+		secret, err := readSecret(kv, secretPath)
+		if err != nil {
+			return archived, 0, fmt.Errorf("unable to read secret: %v", err)
+		}
+		archived.Versions = append(archived.Versions, ArchivedVersion{Data: secret.Data})
+		return archived, 0, nil
+	}
+
+	metadata, err := auth.Client.KVv2(kv.Path).GetMetadata(context.Background(), secretPath)
+	if err != nil {
+		return archived, 0, fmt.Errorf("unable to read metadata: %v", err)
+	}
+	archived.Metadata = &ArchivedMetadata{
+		MaxVersions:    metadata.MaxVersions,
+		CASRequired:    metadata.CASRequired,
+		CustomMetadata: metadata.CustomMetadata,
+	}
+	if metadata.DeleteVersionAfter > 0 {
+		archived.Metadata.DeleteVersionAfter = metadata.DeleteVersionAfter.String()
+	}
+
+	skipped := 0
+	for _, version := range versionList(metadata) {
+		if !allVersions && version.Version != metadata.CurrentVersion {
+			continue
+		}
+		if version.Destroyed || !version.DeletionTime.IsZero() {
+			skipped++
+			continue
+		}
+		secret, err := auth.Client.KVv2(kv.Path).GetVersion(context.Background(), secretPath, version.Version)
+		if err != nil {
+			return archived, 0, fmt.Errorf("unable to read version %d: %v", version.Version, err)
+		}
+		archived.Versions = append(archived.Versions, ArchivedVersion{
+			Version:     version.Version,
+			CreatedTime: formatTime(version.CreatedTime),
+			Data:        secret.Data,
+		})
+	}
+	return archived, skipped, nil
+}
+
+// putArchivedMetadata replaces the settings and custom metadata of a KV
+// version 2 secret with the archived ones.
+func putArchivedMetadata(kv *kvMount, secretPath string, metadata *ArchivedMetadata) error {
+	var deleteAfter time.Duration
+	if metadata.DeleteVersionAfter != "" {
+		var err error
+		if deleteAfter, err = time.ParseDuration(metadata.DeleteVersionAfter); err != nil {
+			return fmt.Errorf("invalid delete_version_after: %v", err)
+		}
+	}
+	err := auth.Client.KVv2(kv.Path).PutMetadata(context.Background(), secretPath, vault.KVMetadataPutInput{
+		CASRequired:        metadata.CASRequired,
+		CustomMetadata:     metadata.CustomMetadata,
+		DeleteVersionAfter: deleteAfter,
+		MaxVersions:        metadata.MaxVersions,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write metadata: %v", err)
+	}
+	return nil
+}
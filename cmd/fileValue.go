@@ -0,0 +1,241 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"cliapp/auth"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// fileMarkerPrefix starts the custom metadata keys that record how a value
+// read from a file was stored, such as "cliapp.file.keystore".
+const fileMarkerPrefix = "cliapp.file."
+
+const (
+	// secretSizeWarning is where Vault's storage starts to struggle: the
+	// integrated storage rejects entries over 1 MiB by default.
+	secretSizeWarning = 512 * 1024
+	// secretSizeLimit is Vault's default max_request_size.
+	secretSizeLimit = 32 * 1024 * 1024
+)
+
+// fileMarker records how a value read from a file was stored, so that the
+// file can be restored by get --output. The checksum of the stored value
+// ties the marker to it: a marker left over after the key was overwritten
+// with a plain value no longer matches and is ignored.
+type fileMarker struct {
+	Encoding string
+	Mode     os.FileMode
+	Checksum string
+}
+
+// encodeFileValue returns the value to store for file contents: the text
+// itself, or base64 for binary files.
+func encodeFileValue(content []byte, mode os.FileMode) (string, fileMarker) {
+	marker := fileMarker{Encoding: "none", Mode: mode}
+	stored := string(content)
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		marker.Encoding = "base64"
+		stored = base64.StdEncoding.EncodeToString(content)
+	}
+	marker.Checksum = valueChecksum(stored)
+	return stored, marker
+}
+
+func valueChecksum(stored string) string {
+	sum := sha256.Sum256([]byte(stored))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (marker fileMarker) String() string {
+	return fmt.Sprintf("encoding=%s;mode=%04o;sha256=%s", marker.Encoding, marker.Mode.Perm(), marker.Checksum)
+}
+
+// parseFileMarker reads a marker from custom metadata, returning false when
+// it is not in the expected format.
+func parseFileMarker(text string) (fileMarker, bool) {
+	var marker fileMarker
+	for _, field := range strings.Split(text, ";") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return marker, false
+		}
+		switch parts[0] {
+		case "encoding":
+			marker.Encoding = parts[1]
+		case "mode":
+			mode, err := strconv.ParseUint(parts[1], 8, 32)
+			if err != nil {
+				return marker, false
+			}
+			marker.Mode = os.FileMode(mode).Perm()
+		case "sha256":
+			marker.Checksum = parts[1]
+		}
+	}
+	return marker, marker.Encoding != "" && marker.Checksum != ""
+}
+
+// requireFileSupport returns the file markers that can be recorded for the
+// secret. Recording them patches the custom metadata, which the admin policy
+// allows but the user policy does not: without it text files are stored
+// without their markers, while binary files are refused, as their encoding
+// could not be restored. KV version 1 mounts have no custom metadata at all.
+func requireFileSupport(kv *kvMount, secretPath string, files map[string]fileMarker) map[string]fileMarker {
+	var binary []string
+	for key, marker := range files {
+		if marker.Encoding != "none" {
+			requireKVv2(kv, fmt.Sprintf("Storing the binary file of '%s'", key))
+			binary = append(binary, key)
+		}
+	}
+	if len(files) == 0 || kv.Version != 2 || canPatchMetadata(kv, secretPath) {
+		return files
+	}
+	if len(binary) > 0 {
+		sort.Strings(binary)
+		fmt.Printf("Error: Storing the binary file of '%s' records its encoding in the metadata of '%s/%s', which needs the patch capability of the admin policy\n", strings.Join(binary, "', '"), kv.Path, secretPath)
+		os.Exit(1)
+	}
+	return nil
+}
+
+// canPatchMetadata reports whether the token can patch the metadata of the
+// secret.
+func canPatchMetadata(kv *kvMount, secretPath string) bool {
+	capabilities, err := auth.Client.Sys().CapabilitiesSelf(kv.Path + "/metadata/" + secretPath)
+	if err != nil {
+		return false
+	}
+	for _, capability := range capabilities {
+		if capability == "patch" || capability == "root" {
+			return true
+		}
+	}
+	return false
+}
+
+// saveFileMarkers records the file markers in the custom metadata of the
+// secret. Text files on KV version 1 mounts are stored without markers.
+func saveFileMarkers(kv *kvMount, secretPath string, files map[string]fileMarker) error {
+	if len(files) == 0 || kv.Version != 2 {
+		return nil
+	}
+
+	custom := map[string]interface{}{}
+	for key, marker := range files {
+		custom[fileMarkerPrefix+key] = marker.String()
+	}
+	err := auth.Client.KVv2(kv.Path).PatchMetadata(context.Background(), secretPath, vault.KVMetadataPatchInput{CustomMetadata: custom})
+	if err != nil {
+		return fmt.Errorf("unable to record the file encoding in the metadata of '%s/%s': %v", kv.Path, secretPath, err)
+	}
+	return nil
+}
+
+// saveFiles records the file markers after a write, stopping the command
+// when they cannot be recorded.
+func saveFiles(kv *kvMount, secretPath string, files map[string]fileMarker) {
+	if err := saveFileMarkers(kv, secretPath, files); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// decodeFieldValue returns the bytes of a field of a secret and the file
+// permissions to restore it with. Values stored from binary files are
+// decoded from base64.
+func decodeFieldValue(secret *vault.KVSecret, key string) ([]byte, os.FileMode, error) {
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, 0, fmt.Errorf("no field '%s'", key)
+	}
+	stored := fieldText(value)
+
+	text, _ := secret.CustomMetadata[fileMarkerPrefix+key].(string)
+	marker, ok := parseFileMarker(text)
+	if !ok || marker.Checksum != valueChecksum(stored) {
+		return []byte(stored), 0600, nil
+	}
+	if marker.Encoding == "base64" {
+		content, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil {
+			return nil, 0, fmt.Errorf("field '%s' is not valid base64: %v", key, err)
+		}
+		return content, marker.Mode, nil
+	}
+	return []byte(stored), marker.Mode, nil
+}
+
+// writeFieldFile writes a field to a file with the given permissions, also
+// when the file exists or the umask would change them.
+func writeFieldFile(file string, content []byte, mode os.FileMode) error {
+	if err := ioutil.WriteFile(file, content, mode); err != nil {
+		return fmt.Errorf("unable to write '%s': %v", file, err)
+	}
+	if err := os.Chmod(file, mode); err != nil {
+		return fmt.Errorf("unable to set the permissions of '%s': %v", file, err)
+	}
+	return nil
+}
+
+// isFileField reports whether a field holds file contents, which are
+// printed without a final newline.
+func isFileField(secret *vault.KVSecret, key string) bool {
+	_, ok := fieldFileMarker(secret, key)
+	return ok
+}
+
+// fieldFileMarker returns the file marker of a field when it matches the
+// stored value.
+func fieldFileMarker(secret *vault.KVSecret, key string) (fileMarker, bool) {
+	text, _ := secret.CustomMetadata[fileMarkerPrefix+key].(string)
+	marker, ok := parseFileMarker(text)
+	return marker, ok && marker.Checksum == valueChecksum(fieldText(secret.Data[key]))
+}
+
+// removeFileMarkers removes the file markers of keys that no longer exist
+// from the custom metadata of the secret.
+func removeFileMarkers(kv *kvMount, secretPath string, keys ...string) error {
+	custom := map[string]interface{}{}
+	for _, key := range keys {
+		custom[fileMarkerPrefix+key] = nil
+	}
+	err := auth.Client.KVv2(kv.Path).PatchMetadata(context.Background(), secretPath, vault.KVMetadataPatchInput{CustomMetadata: custom})
+	if err != nil {
+		return fmt.Errorf("unable to remove the file encoding from the metadata of '%s/%s': %v", kv.Path, secretPath, err)
+	}
+	return nil
+}
+
+// checkSecretSize warns when the data of a secret gets close to what Vault
+// stores comfortably, and stops when Vault would refuse the request.
+func checkSecretSize(data map[string]interface{}) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	size := len(content)
+	if size > secretSizeLimit {
+		fmt.Printf("Error: The secret is %d KiB, more than Vault's default request limit of %d MiB\n", size/1024, secretSizeLimit/1024/1024)
+		os.Exit(1)
+	}
+	if size > secretSizeWarning {
+		fmt.Fprintf(os.Stderr, "Warning: The secret is %d KiB. Vault's integrated storage rejects entries over 1 MiB by default, consider splitting it.\n", size/1024)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var outputFormat string
+
+var outputFormats = []string{"table", "json", "yaml", "env", "raw"}
+
+var envNameInvalid = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// validateFormat checks the global --format flag before a command runs.
+func validateFormat() {
+	for _, format := range outputFormats {
+		if outputFormat == format {
+			return
+		}
+	}
+	fmt.Printf("Error: The format must be one of %s\n", strings.Join(outputFormats, ", "))
+	os.Exit(1)
+}
+
+// printFormatted prints value in the format selected with --format. The
+// table format calls table, which prints the command's usual text.
+func printFormatted(value interface{}, table func()) {
+	switch outputFormat {
+	case "json":
+		printJSON(value)
+	case "yaml":
+		content, err := yaml.Marshal(toPlain(value))
+		if err != nil {
+			log.Fatalf("unable to encode YAML: %v", err)
+		}
+		fmt.Print(string(content))
+	case "env":
+		printEnv(toPlain(value))
+	case "raw":
+		printRaw(toPlain(value))
+	default:
+		table()
+	}
+}
+
+// printJSON prints value as indented JSON.
+func printJSON(value interface{}) {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		log.Fatalf("unable to encode JSON: %v", err)
+	}
+	fmt.Println(string(content))
+}
+
+// toPlain converts value to maps, lists and scalars through its JSON form,
+// so that the yaml, env and raw formats use the same field names as json.
+func toPlain(value interface{}) interface{} {
+	content, err := json.Marshal(value)
+	if err != nil {
+		log.Fatalf("unable to encode output: %v", err)
+	}
+	var plain interface{}
+	if err := json.Unmarshal(content, &plain); err != nil {
+		log.Fatalf("unable to encode output: %v", err)
+	}
+	return plain
+}
+
+// printEnv prints KEY='value' lines that can be sourced by a shell. Nested
+// fields are joined with underscores.
+func printEnv(value interface{}) {
+	lines := map[string]string{}
+	flattenEnv("", value, lines)
+
+	var names []string
+	for name := range lines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s=%s\n", name, lines[name])
+	}
+}
+
+func flattenEnv(prefix string, value interface{}, lines map[string]string) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			name := key
+			if prefix != "" {
+				name = prefix + "_" + key
+			}
+			flattenEnv(name, child, lines)
+		}
+	case []interface{}:
+		if prefix == "" {
+			for i, child := range value {
+				flattenEnv(fmt.Sprintf("ITEM_%d", i), child, lines)
+			}
+			return
+		}
+		lines[envName(prefix)] = shellQuote(compactJSON(value))
+	case string:
+		lines[envName(prefix)] = shellQuote(value)
+	case nil:
+		lines[envName(prefix)] = "''"
+	default:
+		lines[envName(prefix)] = fmt.Sprint(value)
+	}
+}
+
+func envName(name string) string {
+	name = envNameInvalid.ReplaceAllString(strings.ToUpper(name), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// printRaw prints the value without any decoration: strings as they are,
+// lists one item per line and objects as compact JSON.
+func printRaw(value interface{}) {
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				fmt.Println(text)
+			} else {
+				fmt.Println(compactJSON(item))
+			}
+		}
+	case string:
+		fmt.Println(value)
+	default:
+		fmt.Println(compactJSON(value))
+	}
+}
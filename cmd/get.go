@@ -10,22 +10,30 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	vault "github.com/hashicorp/vault/api"
 	"github.com/spf13/cobra"
 )
 
 var (
-	getMount   string
-	getPath    string
-	getVersion int
-	u1         string
-	p1         string
+	getMount     string
+	getPath      string
+	getVersion   int
+	getField     string
+	getFields    []string
+	getQuery     string
+	getTemplate  string
+	getNoNewline bool
+	getOutput    string
+	u1           string
+	p1           string
 )
 
 // getCmd represents the get command
 var getCmd = &cobra.Command{
-	Use:   "get",
+	Use:   "get [mount/path]",
 	Short: "Gets the value from vaults key-value store at a given key name",
 	Long: ` 
 	Retrieves the value from Vault's key-value store at the given key name. If no
@@ -34,21 +42,47 @@ var getCmd = &cobra.Command{
 	with a different version than the current using the "--version" flag 
 	
 	Examples of the write command(Keycloak Authentication):
-		$ ./cliapp get --mount=secret --path=secret/my-secret
+		$ ./cliapp get secret/my-secret
 
-		$ ./cliapp get --mount=secret --path=secret/my-secret --version=2
+		$ ./cliapp get secret/my-secret --version=2
+
+	To print only the value of one or more keys, for use in scripts:
+		$ ./cliapp get secret/my-secret --field=password
+
+		$ ./cliapp get secret/my-secret --field=password --no-newline
+
+		$ ./cliapp get secret/my-secret --fields=username,password
+
+	To select from the secret data and metadata with a JSONPath or a Go template:
+		$ ./cliapp get secret/my-secret --query='$.metadata.version'
+
+		$ ./cliapp get secret/my-secret --template='{{.data.username}}:{{.data.password}}'
+
+	The command exits with a non-zero status if a selected field does not exist.
+
+	A field written from a file with key=@file can be restored to a file with --output,
+	decoding binary files and restoring the permissions the file had when it was written.
+		$ ./cliapp get secret/my-secret --field=keystore --output=keystore.jks
 	
+	The mount is found from the mount table. The mount and the path inside it can also be given with flags:
+		$ ./cliapp get --mount=secret --path=my-secret
+
 	To use with Userpass Authentication:
-		$ ./cliapp get --mount=secret --path=secret/my-secret --user=username --pass=password
+		$ ./cliapp get secret/my-secret --user=username --pass=password
 
 	To use a different instance:
-		$ ./cliapp get --mount=secret --path=secret/my-secret --user=username --pass=password --instance
+		$ ./cliapp get secret/my-secret --user=username --pass=password --instance
 	`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if getVersion < 0 {
 			fmt.Println("Error: Version of secret cannot be less than one")
 			os.Exit(1)
 		}
+		if getOutput != "" && getField == "" {
+			fmt.Println("Error: --output needs the --field to write to the file")
+			os.Exit(1)
+		}
 
 		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
 			address := util.UpdateAddress(instance)
@@ -62,45 +96,23 @@ var getCmd = &cobra.Command{
 			auth.KeycloakAuth(address)
 		}
 
-		util.ValidatePath(getPath)
-		util.ValidatePath(getMount)
+		kv := resolveKVArgs(args, &getMount, &getPath, true)
+		var secret *vault.KVSecret
+		var err error
 		if getVersion == 0 { // get current version
-			secret, err := auth.Client.KVv2(getMount).Get(context.Background(), getPath)
-			if err != nil {
-				log.Fatalf("unable to read secret: %v", err)
-			}
-			key := secret.Data //deleted secret check
-			versio := secret.VersionMetadata.Destroyed
-			secretTimeDeletion := secret.VersionMetadata.DeletionTime.Format("2006-01-02 15:04:05")
-			if key == nil && versio {
-				fmt.Println("Secret Destroyed")
-				fmt.Println("Time Deleted: " + secretTimeDeletion)
-				os.Exit(0)
-			} else if key == nil {
-				fmt.Println("Secret Deleted, not Destroyed")
-				fmt.Println("Time Deleted: " + secretTimeDeletion)
-				os.Exit(0)
-			}
-			printSecret(secret)
+			secret, err = readSecret(kv, getPath)
 		} else {
-			secret, err := auth.Client.KVv2(getMount).GetVersion(context.Background(), getPath, getVersion)
-			if err != nil {
-				log.Fatalf("unable to read secret: %v", err)
-			}
-			key := secret.Data //deleted secret check
-			versio := secret.VersionMetadata.Destroyed
-			secretTimeDeletion := secret.VersionMetadata.DeletionTime.Format("2006-01-02 15:04:05")
-			if key == nil && versio {
-				fmt.Println("Secret Destroyed")
-				fmt.Println("Time Deleted: " + secretTimeDeletion)
-				os.Exit(0)
-			} else if key == nil {
-				fmt.Println("Secret Deleted, not Destroyed")
-				fmt.Println("Time Deleted: " + secretTimeDeletion)
-				os.Exit(0)
-			}
-			printSecret(secret)
+			requireKVv2(kv, "Reading a version")
+			secret, err = auth.Client.KVv2(getMount).GetVersion(context.Background(), getPath, getVersion)
+		}
+		if err != nil {
+			log.Fatalf("unable to read secret: %v", err)
+		}
+		if getField != "" || len(getFields) > 0 || getQuery != "" || getTemplate != "" {
+			printSecretFields(getMount, getPath, secret)
+			return
 		}
+		printSecret(getMount, getPath, secret)
 
 	},
 }
@@ -111,20 +123,21 @@ func init() {
 	//mount
 	getCmd.Flags().StringVarP(&getMount, "mount", "m", "", "The mount path to retrive secrets from")
 
-	if err := getCmd.MarkFlagRequired("mount"); err != nil {
-		fmt.Println(err)
-	}
-
 	// path
 	getCmd.Flags().StringVarP(&getPath, "path", "p", "", "path to the secret")
 
-	if err := getCmd.MarkFlagRequired("path"); err != nil {
-		fmt.Println(err)
-	}
-
 	// version
 	getCmd.Flags().IntVarP(&getVersion, "version", "v", 0, "version of secret")
 
+	// field selection
+	getCmd.Flags().StringVarP(&getField, "field", "f", "", "Print only the value of this key")
+	getCmd.Flags().StringSliceVar(&getFields, "fields", nil, "Print only these keys, comma separated")
+	getCmd.Flags().StringVarP(&getQuery, "query", "q", "", "JSONPath query over the secret data and metadata")
+	getCmd.Flags().StringVarP(&getTemplate, "template", "t", "", "Go template over the secret data and metadata")
+	getCmd.Flags().BoolVarP(&getNoNewline, "no-newline", "n", false, "Do not print a newline after a selected value")
+	getCmd.Flags().StringVarP(&getOutput, "output", "o", "", "Write the --field to this file instead of printing it")
+	getCmd.MarkFlagsMutuallyExclusive("field", "fields", "query", "template")
+
 	// userpass
 	getCmd.Flags().StringVarP(&u1, "user", "u", "", "Userpass username")
 	getCmd.Flags().StringVarP(&p1, "pass", "a", "", "Userpass password")
@@ -134,13 +147,156 @@ func init() {
 
 }
 
-func printSecret(secret *vault.KVSecret) {
-	secretVersion := secret.VersionMetadata.Version
-	secretTimeCreation := secret.VersionMetadata.CreatedTime.Format("2006-01-02 15:04:05")
+// secretOutput is the machine-readable form of a secret printed by get.
+type secretOutput struct {
+	Mount    string                 `json:"mount"`
+	Path     string                 `json:"path"`
+	Data     map[string]interface{} `json:"data"`
+	Metadata secretMetadataOutput   `json:"metadata"`
+}
+
+type secretMetadataOutput struct {
+	Version        int                    `json:"version"`
+	CreatedTime    string                 `json:"created_time"`
+	DeletionTime   string                 `json:"deletion_time,omitempty"`
+	Destroyed      bool                   `json:"destroyed"`
+	CustomMetadata map[string]interface{} `json:"custom_metadata,omitempty"`
+}
+
+func newSecretOutput(mount, secretPath string, secret *vault.KVSecret) secretOutput {
+	output := secretOutput{
+		Mount: mount,
+		Path:  secretPath,
+		Data:  secret.Data,
+	}
+	if secret.VersionMetadata != nil {
+		output.Metadata = secretMetadataOutput{
+			Version:        secret.VersionMetadata.Version,
+			CreatedTime:    formatTime(secret.VersionMetadata.CreatedTime),
+			DeletionTime:   formatTime(secret.VersionMetadata.DeletionTime),
+			Destroyed:      secret.VersionMetadata.Destroyed,
+			CustomMetadata: secret.CustomMetadata,
+		}
+	}
+	return output
+}
+
+func printSecret(mount, secretPath string, secret *vault.KVSecret) {
+	if outputFormat == "env" || outputFormat == "raw" {
+		printFormatted(secret.Data, nil)
+		return
+	}
+
+	printFormatted(newSecretOutput(mount, secretPath, secret), func() {
+		if secret.VersionMetadata == nil { // KV version 1 secret
+			for key, value := range secret.Data {
+				fmt.Printf("Key: %s Value: %s\n", key, fieldText(value))
+			}
+			return
+		}
+
+		key := secret.Data //deleted secret check
+		versio := secret.VersionMetadata.Destroyed
+		secretTimeDeletion := secret.VersionMetadata.DeletionTime.Format("2006-01-02 15:04:05")
+		if key == nil && versio {
+			fmt.Println("Secret Destroyed")
+			fmt.Println("Time Deleted: " + secretTimeDeletion)
+			return
+		} else if key == nil {
+			fmt.Println("Secret Deleted, not Destroyed")
+			fmt.Println("Time Deleted: " + secretTimeDeletion)
+			return
+		}
+
+		secretVersion := secret.VersionMetadata.Version
+		secretTimeCreation := secret.VersionMetadata.CreatedTime.Format("2006-01-02 15:04:05")
+
+		fmt.Printf("Version: %d \n", secretVersion)
+		fmt.Println("Time Created: ", secretTimeCreation)
+		for key, value := range secret.Data {
+			fmt.Printf("Key: %s Value: %s\n", key, fieldText(value))
+		}
+	})
+}
+
+// printSecretFields prints the part of the secret selected with --field,
+// --fields, --query or --template, and exits with status 1 if it is missing.
+func printSecretFields(mount, secretPath string, secret *vault.KVSecret) {
+	newline := "\n"
+	if getNoNewline {
+		newline = ""
+	}
+
+	switch {
+	case getField != "":
+		if _, ok := secret.Data[getField]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: No field '%s' in secret '%s'\n", getField, secretPath)
+			os.Exit(1)
+		}
+		content, mode, err := decodeFieldValue(secret, getField)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if getOutput != "" {
+			if err := writeFieldFile(getOutput, content, mode); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote field '%s' to %s (%d bytes, mode %04o)\n", getField, getOutput, len(content), mode)
+			return
+		}
+		if isFileField(secret, getField) { // file contents end as the file did
+			os.Stdout.Write(content)
+			return
+		}
+		fmt.Print(string(content) + newline)
+	case len(getFields) > 0:
+		fields := map[string]interface{}{}
+		var missing []string
+		for _, field := range getFields {
+			value, ok := secret.Data[field]
+			if !ok {
+				missing = append(missing, field)
+				continue
+			}
+			fields[field] = value
+		}
+		if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: No field '%s' in secret '%s'\n", strings.Join(missing, "', '"), secretPath)
+			os.Exit(1)
+		}
+		printFormatted(fields, func() {
+			for _, field := range getFields {
+				fmt.Println(fieldText(fields[field]))
+			}
+		})
+	case getQuery != "":
+		value, ok, err := evalQuery(toPlain(newSecretOutput(mount, secretPath, secret)), getQuery)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid query: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: The query '%s' did not match anything in secret '%s'\n", getQuery, secretPath)
+			os.Exit(1)
+		}
+		fmt.Print(fieldText(value) + newline)
+	case getTemplate != "":
+		text, err := evalTemplate(toPlain(newSecretOutput(mount, secretPath, secret)), getTemplate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(text + newline)
+	}
+}
 
-	fmt.Printf("Version: %d \n", secretVersion)
-	fmt.Println("Time Created: ", secretTimeCreation)
-	for key, value := range secret.Data {
-		fmt.Printf("Key: %s Value: %v\n", key, value)
+// formatTime formats a Vault timestamp, or returns an empty string for the
+// zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return t.Format(time.RFC3339)
 }
@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyMount string
+	historyPath  string
+	u20          string
+	p20          string
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history [mount/path]",
+	Short: "List every version of a secret",
+	Long: `
+	Lists every version of a secret on a KV version 2 mount with the time it was created,
+	the time it was deleted and whether it is active, deleted or destroyed. Use get with
+	--version to read one of them and diff to compare two of them.
+
+	Example of the history command(Keycloak Authentication):
+		$ ./cliapp history secret/my-secret
+
+		$ ./cliapp history secret/my-secret --format=json
+
+	To use Userpass Authentication:
+		$ ./cliapp history secret/my-secret --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp history secret/my-secret --user=username --pass=password --instance
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u20, p20)
+		kv := resolveKVArgs(args, &historyMount, &historyPath, true)
+		requireKVv2(kv, "Version history")
+
+		metadata, err := auth.Client.KVv2(kv.Path).GetMetadata(context.Background(), historyPath)
+		if err != nil {
+			log.Fatalf("unable to read metadata: %v", err)
+		}
+		versions := newMetadataOutput(kv.Path, historyPath, metadata).Versions
+
+		printFormatted(versions, func() {
+			fmt.Printf("%-8s %-10s %-26s %s\n", "Version", "State", "Created", "Deleted")
+			for _, version := range versions {
+				state := version.State
+				if version.Version == metadata.CurrentVersion {
+					state += "*"
+				}
+				fmt.Printf("%-8d %-10s %-26s %s\n", version.Version, state, version.CreatedTime, version.DeletionTime)
+			}
+			fmt.Println("* current version")
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVarP(&historyMount, "mount", "m", "", "The mount path of the secret")
+	historyCmd.Flags().StringVarP(&historyPath, "path", "p", "", "path to the secret")
+
+	// userpass
+	historyCmd.Flags().StringVarP(&u20, "user", "u", "", "Userpass username")
+	historyCmd.Flags().StringVarP(&p20, "pass", "a", "", "Userpass password")
+	historyCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	historyCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	u14 string
+	p14 string
+)
+
+// errIdentityNotFound is returned when an entity does not exist, as opposed
+// to when it cannot be read.
+var errIdentityNotFound = errors.New("not found")
+
+// identityCmd represents the identity command
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Manage Vault identity entities, aliases and groups",
+	Long: `
+	Vault gives every login an entity. A person logging in with both userpass and Keycloak
+	gets two unrelated entities unless their aliases are linked to the same one. These
+	commands list, read and merge entities, link aliases, manage entity metadata and
+	policies, and manage internal identity groups.
+
+	Examples of the identity commands(Keycloak Authentication):
+		$ ./cliapp identity entity list
+
+		$ ./cliapp identity link --entity=greg --userpass=greg --keycloak-subject=6c1d0a7e-...
+
+		$ ./cliapp identity entity update --name=greg --metadata=team=payments --policies=user-policy
+
+		$ ./cliapp identity group create --name=payments --policies=user-policy --member-entities=greg,anna
+
+	To use Userpass Authentication:
+		$ ./cliapp identity entity list --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp identity entity list --user=username --pass=password --instance
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(identityCmd)
+
+	addLoginFlags(identityCmd, &u14, &p14)
+}
+
+// readEntity reads an entity by name, falling back to its ID.
+func readEntity(nameOrID string) (map[string]interface{}, error) {
+	secret, err := auth.Client.Logical().Read("identity/entity/name/" + nameOrID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read entity '%s': %v", nameOrID, err)
+	}
+	if secret == nil {
+		secret, err = auth.Client.Logical().Read("identity/entity/id/" + nameOrID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read entity '%s': %v", nameOrID, err)
+		}
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("entity '%s' %w", nameOrID, errIdentityNotFound)
+	}
+	return secret.Data, nil
+}
+
+func entityID(nameOrID string) (string, error) {
+	entity, err := readEntity(nameOrID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(entity["id"]), nil
+}
+
+// parseMetadata turns key=value flag values into a metadata map.
+func parseMetadata(pairs []string) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("metadata must be given as key=value, got '%s'", pair)
+		}
+		metadata[parts[0]] = parts[1]
+	}
+	return metadata, nil
+}
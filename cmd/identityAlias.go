@@ -0,0 +1,174 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	aliasEntity string
+	aliasMount  string
+	aliasName   string
+	aliasID     string
+)
+
+// identityAliasCmd represents the identity alias command
+var identityAliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage the aliases of identity entities",
+	Long: `
+	An alias ties a login on an auth mount, such as a userpass username or a Keycloak
+	subject on the jwt mount, to an entity.
+
+	Examples of the identity alias commands(Keycloak Authentication):
+		$ ./cliapp identity alias create --entity=greg --mount=userpass --alias-name=greg
+
+		$ ./cliapp identity alias list
+
+		$ ./cliapp identity alias delete --id=2b7e3c1a-...
+	`,
+}
+
+// identityAliasCreateCmd represents the identity alias create command
+var identityAliasCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Add an alias to an identity entity",
+	Long: `
+	Adds an alias on an auth mount to an entity. If the alias already belongs to another
+	entity, use identity link instead, which merges the entities.
+
+	Example of the identity alias create command(Keycloak Authentication):
+		$ ./cliapp identity alias create --entity=greg --mount=jwt --alias-name=6c1d0a7e-...
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		id, err := entityID(aliasEntity)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		accessor, err := authMountAccessor(aliasMount)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if err := createEntityAlias(aliasName, accessor, id); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		fmt.Printf("Alias '%s' on %s added to entity '%s'.\n", aliasName, aliasMount, aliasEntity)
+	},
+}
+
+// identityAliasListCmd represents the identity alias list command
+var identityAliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List entity aliases",
+	Long: `
+	Lists every entity alias with its mount and the entity it belongs to.
+
+	Example of the identity alias list command(Keycloak Authentication):
+		$ ./cliapp identity alias list
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		secret, err := auth.Client.Logical().List("identity/entity-alias/id")
+		if err != nil {
+			log.Fatalf("unable to list aliases: %v", err)
+		}
+		if secret == nil {
+			fmt.Println("No aliases found.")
+			return
+		}
+
+		info, _ := secret.Data["key_info"].(map[string]interface{})
+		ids := toStrings(secret.Data["keys"])
+		sort.Strings(ids)
+		fmt.Println("Aliases:")
+		for _, id := range ids {
+			entry, _ := info[id].(map[string]interface{})
+			fmt.Printf("%s %v (%v) entity: %v\n", id, entry["name"], entry["mount_path"], entry["canonical_id"])
+		}
+	},
+}
+
+// identityAliasDeleteCmd represents the identity alias delete command
+var identityAliasDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete an entity alias",
+	Long: `
+	Deletes an entity alias by ID. The next login through it creates a new entity.
+
+	Example of the identity alias delete command(Keycloak Authentication):
+		$ ./cliapp identity alias delete --id=2b7e3c1a-...
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		if _, err := auth.Client.Logical().Delete("identity/entity-alias/id/" + aliasID); err != nil {
+			log.Fatalf("unable to delete alias: %v", err)
+		}
+
+		fmt.Printf("Alias '%s' deleted.\n", aliasID)
+	},
+}
+
+func init() {
+	identityCmd.AddCommand(identityAliasCmd)
+	identityAliasCmd.AddCommand(identityAliasCreateCmd)
+	identityAliasCmd.AddCommand(identityAliasListCmd)
+	identityAliasCmd.AddCommand(identityAliasDeleteCmd)
+
+	identityAliasCreateCmd.Flags().StringVarP(&aliasEntity, "entity", "e", "", "Name or ID of the entity")
+	identityAliasCreateCmd.Flags().StringVarP(&aliasMount, "mount", "m", "", "Auth mount of the alias, e.g. userpass or jwt")
+	identityAliasCreateCmd.Flags().StringVarP(&aliasName, "alias-name", "n", "", "Name of the alias, the username or Keycloak subject")
+	for _, flag := range []string{"entity", "mount", "alias-name"} {
+		if err := identityAliasCreateCmd.MarkFlagRequired(flag); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	identityAliasDeleteCmd.Flags().StringVar(&aliasID, "id", "", "ID of the alias")
+	if err := identityAliasDeleteCmd.MarkFlagRequired("id"); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func createEntityAlias(name, accessor, entityID string) error {
+	_, err := auth.Client.Logical().Write("identity/entity-alias", map[string]interface{}{
+		"name":           name,
+		"mount_accessor": accessor,
+		"canonical_id":   entityID,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create alias '%s': %v", name, err)
+	}
+	return nil
+}
+
+// lookupAliasEntity returns the ID of the entity owning the alias, or an
+// empty string when the alias does not exist.
+func lookupAliasEntity(name, accessor string) (string, error) {
+	secret, err := auth.Client.Logical().Write("identity/lookup/entity", map[string]interface{}{
+		"alias_name":           name,
+		"alias_mount_accessor": accessor,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to look up alias '%s': %v", name, err)
+	}
+	if secret == nil || secret.Data["id"] == nil {
+		return "", nil
+	}
+	return fmt.Sprint(secret.Data["id"]), nil
+}
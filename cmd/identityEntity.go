@@ -0,0 +1,348 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	entityName           string
+	entityPolicies       string
+	entityMetadata       []string
+	entityRemoveMetadata []string
+	entityMergeFrom      []string
+	entityMergeForce     bool
+	entityMergeKeep      []string
+)
+
+// identityEntityCmd represents the identity entity command
+var identityEntityCmd = &cobra.Command{
+	Use:   "entity",
+	Short: "List, read, update and merge identity entities",
+	Long: `
+	Manages identity entities. Entities can be given by name or ID.
+
+	Examples of the identity entity commands(Keycloak Authentication):
+		$ ./cliapp identity entity list
+
+		$ ./cliapp identity entity read --name=greg
+
+		$ ./cliapp identity entity create --name=greg --policies=user-policy
+
+		$ ./cliapp identity entity update --name=greg --metadata=team=payments --remove-metadata=old
+
+		$ ./cliapp identity entity merge --name=greg --from=entity_5d3a0c1b
+
+		$ ./cliapp identity entity delete --name=greg
+	`,
+}
+
+// identityEntityListCmd represents the identity entity list command
+var identityEntityListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List identity entities",
+	Long: `
+	Lists every entity with its ID and the number of aliases it has.
+
+	Example of the identity entity list command(Keycloak Authentication):
+		$ ./cliapp identity entity list
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		secret, err := auth.Client.Logical().List("identity/entity/id")
+		if err != nil {
+			log.Fatalf("unable to list entities: %v", err)
+		}
+		if secret == nil {
+			fmt.Println("No entities found.")
+			return
+		}
+
+		info, _ := secret.Data["key_info"].(map[string]interface{})
+		ids := toStrings(secret.Data["keys"])
+		sort.Strings(ids)
+		fmt.Println("Entities:")
+		for _, id := range ids {
+			entry, _ := info[id].(map[string]interface{})
+			aliases, _ := entry["aliases"].([]interface{})
+			fmt.Printf("%s %v (%d aliases)\n", id, entry["name"], len(aliases))
+		}
+	},
+}
+
+// identityEntityReadCmd represents the identity entity read command
+var identityEntityReadCmd = &cobra.Command{
+	Use:   "read",
+	Short: "Show an identity entity and its aliases",
+	Long: `
+	Shows an entity's policies, metadata, groups and aliases.
+
+	Example of the identity entity read command(Keycloak Authentication):
+		$ ./cliapp identity entity read --name=greg
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		entity, err := readEntity(entityName)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("ID: " + fmt.Sprint(entity["id"]))
+		fmt.Println("Name: " + fmt.Sprint(entity["name"]))
+		fmt.Printf("Disabled: %v\n", entity["disabled"])
+		fmt.Printf("Policies: %v\n", toStrings(entity["policies"]))
+		fmt.Printf("Groups: %v\n", toStrings(entity["group_ids"]))
+		if metadata, ok := entity["metadata"].(map[string]interface{}); ok && len(metadata) > 0 {
+			fmt.Println("Metadata:")
+			printFields(metadata)
+		}
+		if aliases, ok := entity["aliases"].([]interface{}); ok {
+			fmt.Println("Aliases:")
+			for _, alias := range aliases {
+				if alias, ok := alias.(map[string]interface{}); ok {
+					fmt.Printf("%v %v (%v)\n", alias["id"], alias["name"], alias["mount_path"])
+				}
+			}
+		}
+	},
+}
+
+// identityEntityCreateCmd represents the identity entity create command
+var identityEntityCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an identity entity",
+	Long: `
+	Creates a named entity, optionally with policies and metadata.
+
+	Example of the identity entity create command(Keycloak Authentication):
+		$ ./cliapp identity entity create --name=greg --policies=user-policy --metadata=team=payments
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		metadata, err := parseMetadata(entityMetadata)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		login(cmd, u14, p14)
+
+		// writing an existing name updates the entity, so check first
+		existing, err := auth.Client.Logical().Read("identity/entity/name/" + entityName)
+		if err != nil {
+			log.Fatalf("unable to read entity '%s': %v", entityName, err)
+		}
+		if existing != nil {
+			fmt.Printf("Entity '%s' already exists.\n", entityName)
+			os.Exit(1)
+		}
+
+		data := map[string]interface{}{
+			"name":     entityName,
+			"policies": splitList(entityPolicies),
+			"metadata": metadata,
+		}
+		secret, err := auth.Client.Logical().Write("identity/entity", data)
+		if err != nil {
+			log.Fatalf("unable to create entity: %v", err)
+		}
+		if secret == nil {
+			fmt.Printf("Entity '%s' already exists.\n", entityName)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Entity '%s' created with ID: %v\n", entityName, secret.Data["id"])
+	},
+}
+
+// identityEntityUpdateCmd represents the identity entity update command
+var identityEntityUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update the policies and metadata of an identity entity",
+	Long: `
+	Updates an entity. The given policies replace the entity's policies. Metadata is merged
+	with the existing metadata, and keys can be removed with --remove-metadata.
+
+	Examples of the identity entity update command(Keycloak Authentication):
+		$ ./cliapp identity entity update --name=greg --policies=user-policy,admin-policy
+
+		$ ./cliapp identity entity update --name=greg --metadata=team=payments --metadata=email=greg@example.com
+
+		$ ./cliapp identity entity update --name=greg --remove-metadata=team
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		metadata, err := parseMetadata(entityMetadata)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		login(cmd, u14, p14)
+
+		entity, err := readEntity(entityName)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		data := map[string]interface{}{}
+		if cmd.Flag("policies").Changed {
+			data["policies"] = splitList(entityPolicies)
+		}
+		if len(entityMetadata) > 0 || len(entityRemoveMetadata) > 0 {
+			merged := map[string]interface{}{}
+			if existing, ok := entity["metadata"].(map[string]interface{}); ok {
+				for k, v := range existing {
+					merged[k] = v
+				}
+			}
+			for k, v := range metadata {
+				merged[k] = v
+			}
+			for _, k := range entityRemoveMetadata {
+				delete(merged, k)
+			}
+			data["metadata"] = merged
+		}
+		if len(data) == 0 {
+			fmt.Println("Error: Nothing to update, provide policies or metadata")
+			os.Exit(1)
+		}
+
+		if _, err := auth.Client.Logical().Write("identity/entity/id/"+fmt.Sprint(entity["id"]), data); err != nil {
+			log.Fatalf("unable to update entity: %v", err)
+		}
+
+		fmt.Printf("Entity '%v' updated.\n", entity["name"])
+	},
+}
+
+// identityEntityMergeCmd represents the identity entity merge command
+var identityEntityMergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Merge identity entities into one",
+	Long: `
+	Merges the entities given with --from into the entity given with --name. Their aliases
+	move to that entity and the merged entities are deleted. If two of the entities have
+	aliases on the same mount the merge fails, unless the ID of the alias to keep on that
+	mount is given with --keep-alias; the other alias is deleted. When the entities have
+	conflicting MFA secrets the merge also fails, unless --force is given to keep the
+	secrets of the entity merged into.
+
+	Example of the identity entity merge command(Keycloak Authentication):
+		$ ./cliapp identity entity merge --name=greg --from=entity_5d3a0c1b --from=entity_9e21f4d7
+
+		$ ./cliapp identity entity merge --name=greg --from=entity_5d3a0c1b --keep-alias=a1b2c3d4-5e6f-7a8b-9c0d-e1f2a3b4c5d6
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		if err := mergeEntities(entityName, entityMergeFrom, entityMergeForce, entityMergeKeep); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Merged %d entities into '%s'.\n", len(entityMergeFrom), entityName)
+	},
+}
+
+// identityEntityDeleteCmd represents the identity entity delete command
+var identityEntityDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete an identity entity",
+	Long: `
+	Deletes an entity and its aliases.
+
+	Example of the identity entity delete command(Keycloak Authentication):
+		$ ./cliapp identity entity delete --name=greg
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		id, err := entityID(entityName)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if _, err := auth.Client.Logical().Delete("identity/entity/id/" + id); err != nil {
+			log.Fatalf("unable to delete entity: %v", err)
+		}
+
+		fmt.Printf("Entity '%s' deleted.\n", entityName)
+	},
+}
+
+func init() {
+	identityCmd.AddCommand(identityEntityCmd)
+	identityEntityCmd.AddCommand(identityEntityListCmd)
+	identityEntityCmd.AddCommand(identityEntityReadCmd)
+	identityEntityCmd.AddCommand(identityEntityCreateCmd)
+	identityEntityCmd.AddCommand(identityEntityUpdateCmd)
+	identityEntityCmd.AddCommand(identityEntityMergeCmd)
+	identityEntityCmd.AddCommand(identityEntityDeleteCmd)
+
+	for _, c := range []*cobra.Command{identityEntityReadCmd, identityEntityCreateCmd, identityEntityUpdateCmd, identityEntityMergeCmd, identityEntityDeleteCmd} {
+		c.Flags().StringVarP(&entityName, "name", "n", "", "Name or ID of the entity")
+		if err := c.MarkFlagRequired("name"); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	for _, c := range []*cobra.Command{identityEntityCreateCmd, identityEntityUpdateCmd} {
+		c.Flags().StringVarP(&entityPolicies, "policies", "p", "", "Comma separated policies of the entity")
+		c.Flags().StringArrayVarP(&entityMetadata, "metadata", "d", nil, "Metadata as key=value (can be repeated)")
+	}
+	identityEntityUpdateCmd.Flags().StringArrayVarP(&entityRemoveMetadata, "remove-metadata", "r", nil, "Metadata key to remove (can be repeated)")
+
+	identityEntityMergeCmd.Flags().StringArrayVarP(&entityMergeFrom, "from", "f", nil, "Name or ID of an entity to merge (can be repeated)")
+	if err := identityEntityMergeCmd.MarkFlagRequired("from"); err != nil {
+		fmt.Println(err)
+	}
+	identityEntityMergeCmd.Flags().BoolVar(&entityMergeForce, "force", false, "Keep the MFA secrets of the target entity when they conflict")
+	identityEntityMergeCmd.Flags().StringArrayVarP(&entityMergeKeep, "keep-alias", "k", nil, "ID of the alias to keep when two entities have aliases on the same mount (can be repeated)")
+}
+
+func mergeEntities(to string, from []string, force bool, keepAliases []string) error {
+	toID, err := entityID(to)
+	if err != nil {
+		return err
+	}
+
+	var fromIDs []string
+	for _, entity := range from {
+		id, err := entityID(entity)
+		if err != nil {
+			return err
+		}
+		if id != toID {
+			fromIDs = append(fromIDs, id)
+		}
+	}
+	if len(fromIDs) == 0 {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"to_entity_id":    toID,
+		"from_entity_ids": fromIDs,
+		"force":           force,
+	}
+	if len(keepAliases) > 0 {
+		data["conflicting_alias_ids_to_keep"] = keepAliases
+	}
+	_, err = auth.Client.Logical().Write("identity/entity/merge", data)
+	if err != nil {
+		return fmt.Errorf("unable to merge entities: %v", err)
+	}
+	return nil
+}
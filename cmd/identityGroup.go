@@ -0,0 +1,361 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	groupName          string
+	groupPolicies      string
+	groupMembers       string
+	groupMemberGroups  string
+	groupAddMembers    string
+	groupRemoveMembers string
+	groupMetadata      []string
+)
+
+// identityGroupCmd represents the identity group command
+var identityGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage internal identity groups",
+	Long: `
+	Internal groups collect entities, and other groups, and give them policies. Members are
+	given by entity name or ID. External groups, which follow Keycloak group membership,
+	are managed by sync groups instead.
+
+	Examples of the identity group commands(Keycloak Authentication):
+		$ ./cliapp identity group create --name=payments --policies=user-policy --member-entities=greg,anna
+
+		$ ./cliapp identity group update --name=payments --add-entities=tom --remove-entities=anna
+
+		$ ./cliapp identity group read --name=payments
+
+		$ ./cliapp identity group list
+
+		$ ./cliapp identity group delete --name=payments
+	`,
+}
+
+// identityGroupListCmd represents the identity group list command
+var identityGroupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List identity groups",
+	Long: `
+	Lists every identity group with its ID and number of member entities.
+
+	Example of the identity group list command(Keycloak Authentication):
+		$ ./cliapp identity group list
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		secret, err := auth.Client.Logical().List("identity/group/id")
+		if err != nil {
+			log.Fatalf("unable to list groups: %v", err)
+		}
+		if secret == nil {
+			fmt.Println("No groups found.")
+			return
+		}
+
+		info, _ := secret.Data["key_info"].(map[string]interface{})
+		ids := toStrings(secret.Data["keys"])
+		sort.Strings(ids)
+		fmt.Println("Groups:")
+		for _, id := range ids {
+			entry, _ := info[id].(map[string]interface{})
+			fmt.Printf("%s %v (%v members)\n", id, entry["name"], entry["num_member_entities"])
+		}
+	},
+}
+
+// identityGroupReadCmd represents the identity group read command
+var identityGroupReadCmd = &cobra.Command{
+	Use:   "read",
+	Short: "Show an identity group",
+	Long: `
+	Shows a group's type, policies, metadata and members.
+
+	Example of the identity group read command(Keycloak Authentication):
+		$ ./cliapp identity group read --name=payments
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		secret, err := auth.Client.Logical().Read("identity/group/name/" + groupName)
+		if err != nil {
+			log.Fatalf("unable to read group: %v", err)
+		}
+		if secret == nil {
+			fmt.Printf("Group '%s' not found.\n", groupName)
+			os.Exit(1)
+		}
+
+		fmt.Println("ID: " + fmt.Sprint(secret.Data["id"]))
+		fmt.Println("Name: " + fmt.Sprint(secret.Data["name"]))
+		fmt.Println("Type: " + fmt.Sprint(secret.Data["type"]))
+		fmt.Printf("Policies: %v\n", toStrings(secret.Data["policies"]))
+		fmt.Printf("Member Entities: %v\n", toStrings(secret.Data["member_entity_ids"]))
+		fmt.Printf("Member Groups: %v\n", toStrings(secret.Data["member_group_ids"]))
+		if metadata, ok := secret.Data["metadata"].(map[string]interface{}); ok && len(metadata) > 0 {
+			fmt.Println("Metadata:")
+			printFields(metadata)
+		}
+	},
+}
+
+// identityGroupCreateCmd represents the identity group create command
+var identityGroupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an internal identity group",
+	Long: `
+	Creates an internal group with policies, members and metadata.
+
+	Example of the identity group create command(Keycloak Authentication):
+		$ ./cliapp identity group create --name=payments --policies=user-policy --member-entities=greg,anna --metadata=owner=greg
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		metadata, err := parseMetadata(groupMetadata)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		login(cmd, u14, p14)
+
+		// writing an existing name updates the group, so check first
+		existing, err := readVaultGroup(groupName)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if existing != nil {
+			fmt.Printf("Group '%s' already exists.\n", groupName)
+			os.Exit(1)
+		}
+
+		members, err := entityIDs(splitList(groupMembers))
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		memberGroups, err := groupIDs(splitList(groupMemberGroups))
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		secret, err := auth.Client.Logical().Write("identity/group", map[string]interface{}{
+			"name":              groupName,
+			"type":              "internal",
+			"policies":          splitList(groupPolicies),
+			"member_entity_ids": members,
+			"member_group_ids":  memberGroups,
+			"metadata":          metadata,
+		})
+		if err != nil {
+			log.Fatalf("unable to create group: %v", err)
+		}
+		if secret == nil {
+			fmt.Printf("Group '%s' already exists.\n", groupName)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Group '%s' created with ID: %v\n", groupName, secret.Data["id"])
+	},
+}
+
+// identityGroupUpdateCmd represents the identity group update command
+var identityGroupUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update an internal identity group",
+	Long: `
+	Updates a group. Policies, members and member groups that are given replace the
+	existing ones, while --add-entities and --remove-entities change single members.
+	Metadata is merged with the existing metadata.
+
+	Examples of the identity group update command(Keycloak Authentication):
+		$ ./cliapp identity group update --name=payments --policies=user-policy,payments-policy
+
+		$ ./cliapp identity group update --name=payments --add-entities=tom --remove-entities=anna
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		metadata, err := parseMetadata(groupMetadata)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		login(cmd, u14, p14)
+
+		secret, err := auth.Client.Logical().Read("identity/group/name/" + groupName)
+		if err != nil {
+			log.Fatalf("unable to read group: %v", err)
+		}
+		if secret == nil {
+			fmt.Printf("Group '%s' not found.\n", groupName)
+			os.Exit(1)
+		}
+		if secret.Data["type"] != "internal" {
+			fmt.Printf("Error: Group '%s' is an external group, its members come from Keycloak\n", groupName)
+			os.Exit(1)
+		}
+
+		data := map[string]interface{}{}
+		if cmd.Flag("policies").Changed {
+			data["policies"] = splitList(groupPolicies)
+		}
+
+		members := toStrings(secret.Data["member_entity_ids"])
+		if cmd.Flag("member-entities").Changed || groupAddMembers != "" || groupRemoveMembers != "" {
+			if cmd.Flag("member-entities").Changed {
+				if members, err = entityIDs(splitList(groupMembers)); err != nil {
+					fmt.Println("Error:", err)
+					os.Exit(1)
+				}
+			}
+			added, err := entityIDs(splitList(groupAddMembers))
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			removed, err := entityIDs(splitList(groupRemoveMembers))
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			data["member_entity_ids"] = changeMembers(members, added, removed)
+		}
+
+		if cmd.Flag("member-groups").Changed {
+			memberGroups, err := groupIDs(splitList(groupMemberGroups))
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			data["member_group_ids"] = memberGroups
+		}
+
+		if len(metadata) > 0 {
+			merged := map[string]interface{}{}
+			if existing, ok := secret.Data["metadata"].(map[string]interface{}); ok {
+				for k, v := range existing {
+					merged[k] = v
+				}
+			}
+			for k, v := range metadata {
+				merged[k] = v
+			}
+			data["metadata"] = merged
+		}
+
+		if len(data) == 0 {
+			fmt.Println("Error: Nothing to update, provide policies, members or metadata")
+			os.Exit(1)
+		}
+
+		if _, err := auth.Client.Logical().Write("identity/group/id/"+fmt.Sprint(secret.Data["id"]), data); err != nil {
+			log.Fatalf("unable to update group: %v", err)
+		}
+
+		fmt.Printf("Group '%s' updated.\n", groupName)
+	},
+}
+
+// identityGroupDeleteCmd represents the identity group delete command
+var identityGroupDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete an identity group",
+	Long: `
+	Deletes a group. Its members lose the group's policies.
+
+	Example of the identity group delete command(Keycloak Authentication):
+		$ ./cliapp identity group delete --name=payments
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u14, p14)
+
+		if _, err := auth.Client.Logical().Delete("identity/group/name/" + groupName); err != nil {
+			log.Fatalf("unable to delete group: %v", err)
+		}
+
+		fmt.Printf("Group '%s' deleted.\n", groupName)
+	},
+}
+
+func init() {
+	identityCmd.AddCommand(identityGroupCmd)
+	identityGroupCmd.AddCommand(identityGroupListCmd)
+	identityGroupCmd.AddCommand(identityGroupReadCmd)
+	identityGroupCmd.AddCommand(identityGroupCreateCmd)
+	identityGroupCmd.AddCommand(identityGroupUpdateCmd)
+	identityGroupCmd.AddCommand(identityGroupDeleteCmd)
+
+	for _, c := range []*cobra.Command{identityGroupReadCmd, identityGroupCreateCmd, identityGroupUpdateCmd, identityGroupDeleteCmd} {
+		c.Flags().StringVarP(&groupName, "name", "n", "", "Name of the group")
+		if err := c.MarkFlagRequired("name"); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	for _, c := range []*cobra.Command{identityGroupCreateCmd, identityGroupUpdateCmd} {
+		c.Flags().StringVarP(&groupPolicies, "policies", "p", "", "Comma separated policies of the group")
+		c.Flags().StringVarP(&groupMembers, "member-entities", "e", "", "Comma separated member entity names or IDs")
+		c.Flags().StringVarP(&groupMemberGroups, "member-groups", "g", "", "Comma separated member group names")
+		c.Flags().StringArrayVarP(&groupMetadata, "metadata", "d", nil, "Metadata as key=value (can be repeated)")
+	}
+	identityGroupUpdateCmd.Flags().StringVar(&groupAddMembers, "add-entities", "", "Comma separated entities to add")
+	identityGroupUpdateCmd.Flags().StringVar(&groupRemoveMembers, "remove-entities", "", "Comma separated entities to remove")
+}
+
+func entityIDs(names []string) ([]string, error) {
+	var ids []string
+	for _, name := range names {
+		id, err := entityID(name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func groupIDs(names []string) ([]string, error) {
+	var ids []string
+	for _, name := range names {
+		group, err := readVaultGroup(name)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			return nil, fmt.Errorf("group '%s' not found", name)
+		}
+		ids = append(ids, group.ID)
+	}
+	return ids, nil
+}
+
+func changeMembers(members, added, removed []string) []string {
+	remove := map[string]bool{}
+	for _, id := range removed {
+		remove[id] = true
+	}
+
+	seen := map[string]bool{}
+	result := []string{}
+	for _, id := range append(members, added...) {
+		if !remove[id] && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
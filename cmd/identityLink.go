@@ -0,0 +1,110 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	linkEntity        string
+	linkUserpass      string
+	linkSubject       string
+	linkUserpassMount string
+	linkJWTMount      string
+)
+
+// identityLinkCmd represents the identity link command
+var identityLinkCmd = &cobra.Command{
+	Use:   "link",
+	Short: "Link a userpass user and a Keycloak subject to one entity",
+	Long: `
+	Makes a userpass username and a Keycloak subject (the "sub" claim used by the jwt role)
+	log in as the same entity. The entity is created if it does not exist. Aliases that do
+	not exist yet are added to it, and if an alias already belongs to another entity, for
+	example because the person has logged in before, that entity is merged into it.
+
+	Examples of the identity link command(Keycloak Authentication):
+		$ ./cliapp identity link --entity=greg --userpass=greg --keycloak-subject=6c1d0a7e-...
+
+	To use Userpass Authentication:
+		$ ./cliapp identity link --entity=greg --userpass=greg --keycloak-subject=6c1d0a7e-... --user=username --pass=password
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if linkUserpass == "" && linkSubject == "" {
+			fmt.Println("Error: A userpass username or Keycloak subject is required")
+			os.Exit(1)
+		}
+
+		login(cmd, u14, p14)
+
+		id, err := entityID(linkEntity)
+		if err != nil && !errors.Is(err, errIdentityNotFound) {
+			log.Fatalf("%v", err)
+		}
+		if err != nil {
+			secret, err := auth.Client.Logical().Write("identity/entity", map[string]interface{}{"name": linkEntity})
+			if err != nil || secret == nil {
+				log.Fatalf("unable to create entity '%s': %v", linkEntity, err)
+			}
+			id = fmt.Sprint(secret.Data["id"])
+			fmt.Printf("Entity '%s' created.\n", linkEntity)
+		}
+
+		links := []struct{ mount, name string }{
+			{linkUserpassMount, linkUserpass},
+			{linkJWTMount, linkSubject},
+		}
+		for _, link := range links {
+			if link.name == "" {
+				continue
+			}
+			accessor, err := authMountAccessor(link.mount)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+
+			owner, err := lookupAliasEntity(link.name, accessor)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			switch owner {
+			case id:
+				fmt.Printf("Alias '%s' on %s already linked.\n", link.name, link.mount)
+			case "":
+				if err := createEntityAlias(link.name, accessor, id); err != nil {
+					log.Fatalf("%v", err)
+				}
+				fmt.Printf("Alias '%s' on %s linked.\n", link.name, link.mount)
+			default:
+				if err := mergeEntities(id, []string{owner}, false, nil); err != nil {
+					log.Fatalf("%v", err)
+				}
+				fmt.Printf("Entity %s of alias '%s' on %s merged.\n", owner, link.name, link.mount)
+			}
+		}
+
+		fmt.Printf("Entity '%s' linked.\n", linkEntity)
+	},
+}
+
+func init() {
+	identityCmd.AddCommand(identityLinkCmd)
+
+	identityLinkCmd.Flags().StringVarP(&linkEntity, "entity", "e", "", "Name or ID of the entity")
+	if err := identityLinkCmd.MarkFlagRequired("entity"); err != nil {
+		fmt.Println(err)
+	}
+	identityLinkCmd.Flags().StringVarP(&linkUserpass, "userpass", "s", "", "Userpass username")
+	identityLinkCmd.Flags().StringVarP(&linkSubject, "keycloak-subject", "k", "", "Keycloak subject (sub claim)")
+	identityLinkCmd.Flags().StringVar(&linkUserpassMount, "userpass-mount", "userpass", "Mount of the userpass auth method")
+	identityLinkCmd.Flags().StringVar(&linkJWTMount, "jwt-mount", "jwt", "Mount of the Keycloak JWT auth method")
+}
@@ -0,0 +1,247 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kvImportIdentities     []string
+	kvImportPassphraseFile string
+	kvImportConflict       string
+	kvImportDryRun         bool
+	u27                    string
+	p27                    string
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file> [mount/prefix]",
+	Short: "Restore an archive written by export, or import keys from files and password managers",
+	Long: `
+	Restores the secrets of an archive written by export, by default to the mount and prefix
+	they were exported from, or under any other mount or prefix, on either instance. An
+	encrypted archive is decrypted with the age identities given with --identity, or else
+	with a passphrase. The versions of each secret are written oldest first, followed by
+	its metadata. Secrets that already exist are handled by the conflict strategy:
+		skip         leave the existing secret as it is (default)
+		overwrite    write the archived versions on top of the existing secret, keeping its
+		             history, and replace its metadata with the archived metadata
+		new-version  write the archived versions on top of the existing secret and only add
+		             the archived custom metadata
+	Only the last version of each secret is restored to a KV version 1 mount. Progress is
+	reported for every secret, and --dry-run only reports what would be done.
+
+	Examples of the import command(Keycloak Authentication):
+		$ ./cliapp import secret.json.age --dry-run
+
+		$ ./cliapp import team.yaml.age kv/restored/team --identity=key.txt --conflict=new-version
+
+		$ ./cliapp import team.json.age secret/team --passphrase-file=backup.pass --conflict=overwrite
+
+	Keys are also imported from dotenv (.env), YAML (.yaml, .yml), CSV (.csv, with path,key,value
+	rows) and Java properties (.properties) files; --type overrides the type found from the
+	file name. Dotenv values can be quoted, with escapes and several lines in double quotes.
+	In YAML files nested mappings are folders. Keys of dotenv and properties files go to the
+	given mount/path, and CSV rows and YAML folders under it, unless a --map rule
+	GLOB=PATH[:KEY] matches the key (or path/key): the first matching rule gives the secret
+	path under the mount/path and the key, where {1}, {2}... are the text matched by each *,
+	and {path} and {key} the entry's own. The keys are merged into existing secrets, or
+	replace their keys with --replace. A plan listing the keys added, changed and removed
+	in each secret (without the values) is printed first and the secrets are written after
+	confirmation, with check-and-set against the versions the plan was made from.
+		$ ./cliapp import .env secret/app --dry-run
+
+		$ ./cliapp import app.properties secret/app --map='db.*=db:{1}' --map='*=config' --yes
+
+		$ ./cliapp import secrets.csv secret/team
+
+	Exports of password managers are imported the same way, one secret per entry at the
+	path of its folders and title, with the keys username, password, url, notes and totp,
+	custom fields under their own names and attachments as attachment.<name> file values:
+	KeePass 2 XML (.xml), unencrypted Bitwarden JSON (.json) and 1Password CSV (.csv with a
+	Title column). The recycle bin, Bitwarden cards and identities, archived 1Password items
+	and entries without values are skipped and listed; entries with the same path are
+	numbered.
+		$ ./cliapp import keepass.xml secret/shared --dry-run
+
+		$ ./cliapp import bitwarden.json secret/shared --type=bitwarden
+
+	To use Userpass Authentication:
+		$ ./cliapp import secret.json.age --user=username --pass=password
+
+	To restore to a different instance:
+		$ ./cliapp import secret.json.age --user=username --pass=password --instance
+	`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if kvImportConflict != "skip" && kvImportConflict != "overwrite" && kvImportConflict != "new-version" {
+			fmt.Println("Error: The conflict strategy must be skip, overwrite or new-version")
+			os.Exit(1)
+		}
+
+		validType := false
+		for _, fileType := range importFileTypes {
+			validType = validType || kvImportType == fileType
+		}
+		if !validType {
+			fmt.Printf("Error: The file type must be one of %s\n", strings.Join(importFileTypes, ", "))
+			os.Exit(1)
+		}
+
+		content, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("unable to read file: %v", err)
+		}
+		fileType := kvImportType
+		if fileType == "auto" {
+			fileType = importFileType(args[0], content)
+		}
+		if fileType != "archive" {
+			importKeys(cmd, args, content, fileType)
+			return
+		}
+
+		if isEncryptedArchive(content) {
+			content, err = decryptArchive(content, kvImportIdentities, func() (string, error) {
+				return readPassphrase(kvImportPassphraseFile, false)
+			})
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		archive, err := unmarshalArchive(content)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if len(archive.Secrets) == 0 {
+			fmt.Printf("Error: The archive '%s' has no secrets\n", args[0])
+			os.Exit(1)
+		}
+
+		login(cmd, u27, p27)
+		target := joinSecretPath(archive.Mount, archive.Prefix)
+		if len(args) == 2 {
+			target = args[1]
+		}
+		kv, prefix, err := lookupKVMount(target)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if kv.Version != 2 {
+			for _, secret := range archive.Secrets {
+				if len(secret.Versions) > 1 || secret.Metadata != nil {
+					fmt.Fprintf(os.Stderr, "Warning: '%s' is a KV version 1 mount, only the last version of each secret is restored and no metadata.\n", kv.Path)
+					break
+				}
+			}
+		}
+
+		counts := map[string]int{}
+		for i, secret := range archive.Secrets {
+			secretPath := joinSecretPath(prefix, secret.Path)
+			action, err := importSecret(kv, secretPath, secret)
+			if err != nil {
+				counts["failed"]++
+				fmt.Printf("[%d/%d] %s/%s: failed: %v\n", i+1, len(archive.Secrets), kv.Path, secretPath, err)
+				continue
+			}
+			counts[action]++
+			fmt.Printf("[%d/%d] %s/%s: %s (%d versions)\n", i+1, len(archive.Secrets), kv.Path, secretPath, action, len(secret.Versions))
+		}
+
+		summary := "Imported"
+		if kvImportDryRun {
+			summary = "Dry run, would import"
+		}
+		fmt.Printf("%s %d secrets into '%s/%s': %d created, %d overwritten, %d new versions, %d skipped, %d failed.\n",
+			summary, len(archive.Secrets), kv.Path, prefix, counts["created"], counts["overwritten"], counts["new version"], counts["skipped"], counts["failed"])
+		if counts["failed"] > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringArrayVarP(&kvImportIdentities, "identity", "k", nil, "age identity file to decrypt the archive with, can be repeated")
+	importCmd.Flags().StringVar(&kvImportPassphraseFile, "passphrase-file", "", "Decrypt the archive with the passphrase in this file")
+	importCmd.Flags().StringVarP(&kvImportConflict, "conflict", "c", "skip", "What to do with existing secrets: skip, overwrite or new-version")
+	importCmd.Flags().BoolVarP(&kvImportDryRun, "dry-run", "d", false, "Only report what would be imported")
+
+	// key files
+	importCmd.Flags().StringVarP(&kvImportType, "type", "t", "auto", "File type: auto, archive, dotenv, yaml, csv, properties, keepass, bitwarden or 1password")
+	importCmd.Flags().StringArrayVar(&kvImportMaps, "map", nil, "Mapping rule GLOB=PATH[:KEY] for the keys of a file, can be repeated")
+	importCmd.Flags().BoolVar(&kvImportSkipUnmapped, "skip-unmapped", false, "Skip the keys no --map rule matches")
+	importCmd.Flags().BoolVar(&kvImportReplace, "replace", false, "Replace the keys of existing secrets instead of merging into them")
+	importCmd.Flags().BoolVarP(&kvImportYes, "yes", "y", false, "Write without asking for confirmation after the plan")
+
+	// userpass
+	importCmd.Flags().StringVarP(&u27, "user", "u", "", "Userpass username")
+	importCmd.Flags().StringVarP(&p27, "pass", "a", "", "Userpass password")
+	importCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	importCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// importSecret restores one archived secret following the conflict strategy
+// and returns what was done: created, overwritten, new version or skipped.
+func importSecret(kv *kvMount, secretPath string, secret ArchivedSecret) (string, error) {
+	if len(secret.Versions) == 0 {
+		return "skipped", nil
+	}
+	_, err := readSecret(kv, secretPath)
+	if err != nil && !errors.Is(err, vault.ErrSecretNotFound) {
+		return "", fmt.Errorf("unable to read secret: %v", err)
+	}
+	exists := err == nil
+
+	action := "created"
+	if exists {
+		switch kvImportConflict {
+		case "skip":
+			return "skipped", nil
+		case "overwrite":
+			action = "overwritten"
+		default:
+			action = "new version"
+		}
+	}
+	if kvImportDryRun {
+		return action, nil
+	}
+
+	// the existing versions are kept, so a failed write loses nothing
+	if _, err := writeVersions(kv, secretPath, secret.Versions); err != nil {
+		return "", err
+	}
+	if secret.Metadata == nil || kv.Version != 2 {
+		return action, nil
+	}
+	if action != "new version" {
+		return action, putArchivedMetadata(kv, secretPath, secret.Metadata)
+	}
+	if len(secret.Metadata.CustomMetadata) == 0 { // keep the existing settings
+		return action, nil
+	}
+	err = auth.Client.KVv2(kv.Path).PatchMetadata(context.Background(), secretPath, vault.KVMetadataPatchInput{CustomMetadata: secret.Metadata.CustomMetadata})
+	if err != nil {
+		return "", fmt.Errorf("unable to write metadata: %v", err)
+	}
+	return action, nil
+}
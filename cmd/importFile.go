@@ -0,0 +1,215 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kvImportType         string
+	kvImportMaps         []string
+	kvImportSkipUnmapped bool
+	kvImportReplace      bool
+	kvImportYes          bool
+)
+
+var importFileTypes = []string{"auto", "archive", "dotenv", "yaml", "csv", "properties", "keepass", "bitwarden", "1password"}
+
+// importPlanItem is a secret written by a file import, with the version it
+// was read at for check-and-set.
+type importPlanItem struct {
+	Path    string
+	Version int
+	Data    map[string]interface{}
+	Files   map[string]fileMarker
+	Changes []keyChange
+}
+
+// importKeys imports the keys of a dotenv, YAML, CSV or properties file:
+// maps them to secrets, prints the plan and writes the secrets with
+// check-and-set against the versions the plan was made from.
+func importKeys(cmd *cobra.Command, args []string, content []byte, fileType string) {
+	if len(args) != 2 {
+		fmt.Println("Error: The mount/path to import into is required")
+		os.Exit(1)
+	}
+	var rules []mapRule
+	for _, rule := range kvImportMaps {
+		parsed, err := parseMapRule(rule)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		rules = append(rules, parsed)
+	}
+
+	entries, skippedEntries, err := parseImportFile(content, fileType)
+	if err != nil {
+		fmt.Printf("Error: Invalid %s file '%s': %v\n", fileType, args[0], err)
+		os.Exit(1)
+	}
+	if len(skippedEntries) > 0 {
+		fmt.Printf("%d entries skipped or renamed:\n", len(skippedEntries))
+		for _, entry := range skippedEntries {
+			fmt.Println("  " + entry)
+		}
+	}
+	secrets, files, skipped, err := mapEntries(entries, rules, kvImportSkipUnmapped)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	if len(secrets) == 0 {
+		fmt.Printf("Error: No keys to import from '%s'\n", args[0])
+		os.Exit(1)
+	}
+
+	login(cmd, u27, p27)
+	kv, prefix, err := lookupKVMount(args[1])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	requireKVv2(kv, "Check-and-set")
+
+	paths := make([]string, 0, len(secrets))
+	for secretPath := range secrets {
+		paths = append(paths, secretPath)
+	}
+	sort.Strings(paths)
+
+	var plan []importPlanItem
+	for _, secretPath := range paths {
+		fullPath := joinSecretPath(prefix, secretPath)
+		if fullPath == "" {
+			fmt.Println("Error: Keys without a path need a mount/path to import into, or a --map rule")
+			os.Exit(1)
+		}
+		current, version, err := readForUpdate(kv, fullPath)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		data := applyChanges(current, secrets[secretPath], kvImportReplace)
+		plan = append(plan, importPlanItem{Path: fullPath, Version: version, Data: data, Files: requireFileSupport(kv, fullPath, files[secretPath]), Changes: diffSecrets(current, data)})
+	}
+
+	writes := printImportPlan(kv, plan)
+	if skipped > 0 {
+		fmt.Printf("%d unmapped keys skipped.\n", skipped)
+	}
+	if kvImportDryRun || writes == 0 {
+		return
+	}
+	if !kvImportYes && !confirm(fmt.Sprintf("Write %d secrets?", writes)) {
+		fmt.Println("Aborted.")
+		return
+	}
+
+	written, failed := 0, 0
+	for _, item := range plan {
+		if len(item.Changes) == 0 {
+			continue
+		}
+		err := writeSecret(kv, item.Path, item.Data, vault.WithCheckAndSet(item.Version))
+		if isCASError(err) {
+			err = fmt.Errorf("the secret was changed since the plan was made, run the import again")
+		}
+		if err == nil {
+			err = saveFileMarkers(kv, item.Path, item.Files)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("Error: %s/%s: %v\n", kv.Path, item.Path, err)
+			continue
+		}
+		written++
+	}
+	fmt.Printf("Wrote %d secrets.\n", written)
+	if failed > 0 {
+		fmt.Printf("%d secrets failed.\n", failed)
+		os.Exit(1)
+	}
+}
+
+// mapEntries groups the entries into secrets by path, applying the first
+// matching rule to each entry. Entries no rule matches keep their own path
+// and key, or are skipped with skipUnmapped. The file markers of the
+// secrets are returned by path too.
+func mapEntries(entries []importEntry, rules []mapRule, skipUnmapped bool) (map[string]map[string]interface{}, map[string]map[string]fileMarker, int, error) {
+	secrets := map[string]map[string]interface{}{}
+	files := map[string]map[string]fileMarker{}
+	sources := map[string]string{}
+	skipped := 0
+	for _, entry := range entries {
+		secretPath, key, mapped := entry.Path, entry.Key, false
+		for _, rule := range rules {
+			if secretPath, key, mapped = rule.apply(entry); mapped {
+				break
+			}
+		}
+		if !mapped {
+			if skipUnmapped {
+				skipped++
+				continue
+			}
+			secretPath, key = entry.Path, entry.Key
+		}
+		if key == "" {
+			return nil, nil, 0, fmt.Errorf("'%s' is mapped to an empty key", entry.name())
+		}
+
+		target := joinSecretPath(secretPath, key)
+		if source, ok := sources[target]; ok {
+			return nil, nil, 0, fmt.Errorf("'%s' and '%s' are both mapped to '%s'", source, entry.name(), target)
+		}
+		sources[target] = entry.name()
+		if secrets[secretPath] == nil {
+			secrets[secretPath] = map[string]interface{}{}
+		}
+		secrets[secretPath][key] = entry.Value
+		if entry.File != nil {
+			if files[secretPath] == nil {
+				files[secretPath] = map[string]fileMarker{}
+			}
+			files[secretPath][key] = *entry.File
+		}
+	}
+	return secrets, files, skipped, nil
+}
+
+// printImportPlan prints the keys each secret gains, changes or loses,
+// without their values, and returns the number of secrets to write.
+func printImportPlan(kv *kvMount, plan []importPlanItem) int {
+	writes := 0
+	for _, item := range plan {
+		state := fmt.Sprintf("update version %d", item.Version)
+		if item.Version == 0 {
+			state = "create"
+		}
+		if len(item.Changes) == 0 {
+			fmt.Printf("%s/%s: no changes\n", kv.Path, item.Path)
+			continue
+		}
+		writes++
+		fmt.Printf("%s/%s: %s\n", kv.Path, item.Path, state)
+		for _, change := range item.Changes {
+			switch change.Change {
+			case "added":
+				fmt.Printf("  + %s\n", change.Key)
+			case "removed":
+				fmt.Printf("  - %s\n", change.Key)
+			default:
+				fmt.Printf("  ~ %s\n", change.Key)
+			}
+		}
+	}
+	fmt.Printf("%d secrets to write, %d unchanged.\n", writes, len(plan)-writes)
+	return writes
+}
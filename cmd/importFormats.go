@@ -0,0 +1,397 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// importEntry is one key read from a file to import. Formats without paths
+// leave Path empty. File is set for the attachments of password managers.
+type importEntry struct {
+	Path  string
+	Key   string
+	Value interface{}
+	File  *fileMarker
+}
+
+// name is what mapping rules match: the key, prefixed by its path if any.
+func (entry importEntry) name() string {
+	if entry.Path == "" {
+		return entry.Key
+	}
+	return entry.Path + "/" + entry.Key
+}
+
+var dotenvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// importFileType guesses the type of a file to import from its name and
+// content: dotenv, properties, csv, yaml, a password manager export or an
+// export archive.
+func importFileType(file string, content []byte) string {
+	base := strings.ToLower(filepath.Base(file))
+	switch {
+	case strings.HasSuffix(base, ".xml"):
+		return "keepass"
+	case strings.HasSuffix(base, ".json"):
+		if _, err := unmarshalArchive(content); err != nil && bytes.Contains(content, []byte(`"items"`)) {
+			return "bitwarden"
+		}
+	case strings.HasSuffix(base, ".csv") && isOnePasswordCSV(content):
+		return "1password"
+	case strings.HasSuffix(base, ".age"):
+		return "archive"
+	case base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env"):
+		return "dotenv"
+	case strings.HasSuffix(base, ".properties"):
+		return "properties"
+	case strings.HasSuffix(base, ".csv"):
+		return "csv"
+	case isYAMLFile(base):
+		if _, err := unmarshalArchive(content); err != nil {
+			return "yaml"
+		}
+	}
+	return "archive"
+}
+
+// parseImportFile reads the keys of a file to import. Password manager
+// exports also return the entries that were skipped or renamed.
+func parseImportFile(content []byte, fileType string) ([]importEntry, []string, error) {
+	var entries []importEntry
+	var items []managerItem
+	var skipped []string
+	var err error
+	switch fileType {
+	case "dotenv":
+		entries, err = parseDotenv(content)
+	case "properties":
+		entries, err = parseProperties(content)
+	case "csv":
+		entries, err = parseImportCSV(content)
+	case "yaml":
+		entries, err = parseImportYAML(content)
+	case "keepass":
+		items, skipped, err = parseKeePass(content)
+	case "bitwarden":
+		items, skipped, err = parseBitwarden(content)
+	case "1password":
+		items, skipped, err = parseOnePassword(content)
+	default:
+		err = fmt.Errorf("unknown file type '%s'", fileType)
+	}
+	if err != nil || items == nil {
+		return entries, skipped, err
+	}
+	entries, skipped = managerEntries(items, skipped)
+	return entries, skipped, nil
+}
+
+// parseDotenv parses KEY=value lines, optionally prefixed by export. Values
+// in single quotes are literal, values in double quotes understand \n, \t,
+// \r, \", \\ and \$ escapes, and both can span several lines. Unquoted
+// values end at a " #" comment. Variables are not expanded.
+func parseDotenv(content []byte) ([]importEntry, error) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	var entries []importEntry
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		equals := strings.Index(line, "=")
+		if equals < 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", number)
+		}
+		key := strings.TrimSpace(line[:equals])
+		if !dotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid key '%s'", number, key)
+		}
+		rest := strings.TrimLeft(line[equals+1:], " \t")
+
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			if comment := strings.Index(rest, " #"); comment >= 0 {
+				rest = rest[:comment]
+			}
+			entries = append(entries, importEntry{Key: key, Value: strings.TrimSpace(rest)})
+			continue
+		}
+
+		quote := rest[0]
+		rest = rest[1:]
+		var value strings.Builder
+		for {
+			end := closingQuote(rest, quote)
+			if end >= 0 {
+				value.WriteString(rest[:end])
+				if trailing := strings.TrimSpace(rest[end+1:]); trailing != "" && !strings.HasPrefix(trailing, "#") {
+					return nil, fmt.Errorf("line %d: unexpected text after the closing quote", i+1)
+				}
+				break
+			}
+			value.WriteString(rest + "\n")
+			i++
+			if i == len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", number)
+			}
+			rest = lines[i]
+		}
+
+		text := value.String()
+		if quote == '"' {
+			text = unescapeDotenv(text)
+		}
+		entries = append(entries, importEntry{Key: key, Value: text})
+	}
+	return entries, nil
+}
+
+// closingQuote returns the index of the quote ending the value, skipping
+// escaped double quotes.
+func closingQuote(text string, quote byte) int {
+	for i := 0; i < len(text); i++ {
+		if quote == '"' && text[i] == '\\' {
+			i++
+			continue
+		}
+		if text[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i == len(text)-1 {
+			out.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '"', '\\', '$':
+			out.WriteByte(text[i])
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(text[i])
+		}
+	}
+	return out.String()
+}
+
+// parseProperties parses a Java properties file: # and ! comments, keys
+// ended by =, : or whitespace, lines continued with a trailing backslash
+// and \t, \n, \r, \f and \uXXXX escapes.
+func parseProperties(content []byte) ([]importEntry, error) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	var entries []importEntry
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continues(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continues(line) {
+			line = line[:len(line)-1]
+		}
+
+		end := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if line[j] == '=' || line[j] == ':' || line[j] == ' ' || line[j] == '\t' || line[j] == '\f' {
+				end = j
+				break
+			}
+		}
+		rest := strings.TrimLeft(line[end:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+
+		key, err := unescapeProperties(line[:end])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", number, err)
+		}
+		value, err := unescapeProperties(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", number, err)
+		}
+		entries = append(entries, importEntry{Key: key, Value: value})
+	}
+	return entries, nil
+}
+
+// continues reports whether a properties line ends with an odd number of
+// backslashes, which joins it with the next line.
+func continues(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+func unescapeProperties(text string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i == len(text)-1 {
+			out.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 't':
+			out.WriteByte('\t')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 'f':
+			out.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(text) {
+				return "", fmt.Errorf("invalid \\u escape")
+			}
+			code, err := strconv.ParseUint(text[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\u escape '%s'", text[i-1:i+5])
+			}
+			out.WriteRune(rune(code))
+			i += 4
+		default:
+			out.WriteByte(text[i])
+		}
+	}
+	return out.String(), nil
+}
+
+// parseImportCSV parses path,key,value rows, with an optional header row.
+func parseImportCSV(content []byte) ([]importEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = 3
+	var entries []importEntry
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if number == 1 && strings.EqualFold(record[0], "path") && strings.EqualFold(record[1], "key") && strings.EqualFold(record[2], "value") {
+			continue
+		}
+		if record[1] == "" {
+			return nil, fmt.Errorf("row %d: the key is empty", number)
+		}
+		entries = append(entries, importEntry{Path: strings.Trim(record[0], "/"), Key: record[1], Value: record[2]})
+	}
+	return entries, nil
+}
+
+// parseImportYAML reads a YAML mapping. Scalars and lists are keys of the
+// secret at the current path, and nested mappings are folders, so a flat
+// file is a single secret and a mapping of mappings is one secret per path.
+func parseImportYAML(content []byte) ([]importEntry, error) {
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	var entries []importEntry
+	var walk func(folder string, values map[string]interface{})
+	walk = func(folder string, values map[string]interface{}) {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if nested, ok := values[key].(map[string]interface{}); ok {
+				walk(joinSecretPath(folder, key), nested)
+				continue
+			}
+			entries = append(entries, importEntry{Path: folder, Key: key, Value: values[key]})
+		}
+	}
+	walk("", document)
+	return entries, nil
+}
+
+// mapRule maps the entries whose name matches the glob to a secret path and
+// key. The path and key can use the text matched by each * as {1}, {2}...,
+// and the entry's own {path} and {key}.
+type mapRule struct {
+	glob    string
+	pattern *regexp.Regexp
+	path    string
+	key     string
+}
+
+// parseMapRule parses a GLOB=PATH[:KEY] rule. The key is {key} by default.
+func parseMapRule(rule string) (mapRule, error) {
+	equals := strings.Index(rule, "=")
+	if equals <= 0 {
+		return mapRule{}, fmt.Errorf("invalid mapping rule '%s', expected GLOB=PATH[:KEY]", rule)
+	}
+	parsed := mapRule{glob: rule[:equals], path: rule[equals+1:], key: "{key}"}
+	if colon := strings.LastIndex(parsed.path, ":"); colon >= 0 {
+		parsed.path, parsed.key = parsed.path[:colon], parsed.path[colon+1:]
+	}
+	if parsed.key == "" {
+		return mapRule{}, fmt.Errorf("invalid mapping rule '%s', the key is empty", rule)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i, part := range strings.Split(parsed.glob, "*") {
+		if i > 0 {
+			pattern.WriteString("(.*)")
+		}
+		pattern.WriteString(regexp.QuoteMeta(part))
+	}
+	pattern.WriteString("$")
+	parsed.pattern = regexp.MustCompile(pattern.String())
+	return parsed, nil
+}
+
+// apply returns the path and key of the entry if the rule matches it.
+func (rule mapRule) apply(entry importEntry) (string, string, bool) {
+	match := rule.pattern.FindStringSubmatch(entry.name())
+	if match == nil {
+		return "", "", false
+	}
+	replacements := []string{"{path}", entry.Path, "{key}", entry.Key}
+	for i, group := range match[1:] {
+		replacements = append(replacements, "{"+strconv.Itoa(i+1)+"}", group)
+	}
+	replacer := strings.NewReplacer(replacements...)
+	return strings.Trim(replacer.Replace(rule.path), "/"), replacer.Replace(rule.key), true
+}
@@ -0,0 +1,344 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []importEntry
+		wantErr string
+	}{
+		{
+			name:    "unquoted values and comments",
+			content: "# comment\n\nA=1\nexport B = two words # note\nC=\n",
+			want: []importEntry{
+				{Key: "A", Value: "1"},
+				{Key: "B", Value: "two words"},
+				{Key: "C", Value: ""},
+			},
+		},
+		{
+			name:    "single quotes are literal",
+			content: `A='x\ny #z'` + "\n",
+			want:    []importEntry{{Key: "A", Value: `x\ny #z`}},
+		},
+		{
+			name:    "double quote escapes",
+			content: `A="tab\there\nnew \"quoted\" \\ \$HOME \q"` + "\n",
+			want:    []importEntry{{Key: "A", Value: "tab\there\nnew \"quoted\" \\ $HOME \\q"}},
+		},
+		{
+			name:    "multi-line values",
+			content: "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT='a\nb' # comment\n",
+			want: []importEntry{
+				{Key: "KEY", Value: "-----BEGIN-----\nabc\n-----END-----"},
+				{Key: "NEXT", Value: "a\nb"},
+			},
+		},
+		{
+			name:    "windows line endings",
+			content: "A=1\r\nB=\"x\r\ny\"\r\n",
+			want: []importEntry{
+				{Key: "A", Value: "1"},
+				{Key: "B", Value: "x\ny"},
+			},
+		},
+		{
+			name:    "missing equals",
+			content: "A=1\nB\n",
+			wantErr: "line 2: expected KEY=value",
+		},
+		{
+			name:    "invalid key",
+			content: "1A=x\n",
+			wantErr: "line 1: invalid key '1A'",
+		},
+		{
+			name:    "unterminated quote",
+			content: "A=1\nB=\"open\nstill open\n",
+			wantErr: "line 2: unterminated quoted value",
+		},
+		{
+			name:    "text after the closing quote",
+			content: "A=\"x\" y\n",
+			wantErr: "line 1: unexpected text after the closing quote",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseDotenv([]byte(test.content))
+			checkImportEntries(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []importEntry
+		wantErr string
+	}{
+		{
+			name:    "separators and comments",
+			content: "# comment\n! also a comment\na=1\nb: 2\nc 3\n  d = 4 \ne\n",
+			want: []importEntry{
+				{Key: "a", Value: "1"},
+				{Key: "b", Value: "2"},
+				{Key: "c", Value: "3"},
+				{Key: "d", Value: "4 "},
+				{Key: "e", Value: ""},
+			},
+		},
+		{
+			name:    "continuation lines",
+			content: "list = one, \\\n    two, \\\n    three\nnext=x\n",
+			want: []importEntry{
+				{Key: "list", Value: "one, two, three"},
+				{Key: "next", Value: "x"},
+			},
+		},
+		{
+			name:    "escaped backslash does not continue",
+			content: "path=C:\\\\\nnext=x\n",
+			want: []importEntry{
+				{Key: "path", Value: `C:\`},
+				{Key: "next", Value: "x"},
+			},
+		},
+		{
+			name:    "continuation at the end of the file",
+			content: "a=x\\",
+			want:    []importEntry{{Key: "a", Value: "x"}},
+		},
+		{
+			name:    "escapes in keys and values",
+			content: "my\\ key\\=x=tab\\there\\nline \\u00e9\\\\\n",
+			want:    []importEntry{{Key: "my key=x", Value: "tab\there\nline é\\"}},
+		},
+		{
+			name:    "invalid unicode escape",
+			content: "a=ok\nb=\\u12G4\n",
+			wantErr: "line 2: invalid \\u escape '\\u12G4'",
+		},
+		{
+			name:    "truncated unicode escape",
+			content: "a=\\u12\n",
+			wantErr: "line 1: invalid \\u escape",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseProperties([]byte(test.content))
+			checkImportEntries(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []importEntry
+		wantErr string
+	}{
+		{
+			name:    "header row",
+			content: "Path,Key,Value\n/app/db/,user,admin\napp/db,password,\"a,b\"\n",
+			want: []importEntry{
+				{Path: "app/db", Key: "user", Value: "admin"},
+				{Path: "app/db", Key: "password", Value: "a,b"},
+			},
+		},
+		{
+			name:    "no header row",
+			content: "app,key,value\n,top,level\n",
+			want: []importEntry{
+				{Path: "app", Key: "key", Value: "value"},
+				{Path: "", Key: "top", Value: "level"},
+			},
+		},
+		{
+			name:    "header only in the first row",
+			content: "app,key,value\npath,key,value\n",
+			want: []importEntry{
+				{Path: "app", Key: "key", Value: "value"},
+				{Path: "path", Key: "key", Value: "value"},
+			},
+		},
+		{
+			name:    "empty key",
+			content: "path,key,value\napp,,x\n",
+			wantErr: "row 2: the key is empty",
+		},
+		{
+			name:    "wrong number of fields",
+			content: "app,key\n",
+			wantErr: "wrong number of fields",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseImportCSV([]byte(test.content))
+			checkImportEntries(t, got, err, test.want, test.wantErr)
+		})
+	}
+}
+
+func TestMapRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		entry    importEntry
+		wantPath string
+		wantKey  string
+		wantOK   bool
+	}{
+		{
+			name:     "capture groups",
+			rule:     "DB_*_*=app/{1}:{2}",
+			entry:    importEntry{Key: "DB_PROD_PASSWORD"},
+			wantPath: "app/PROD",
+			wantKey:  "PASSWORD",
+			wantOK:   true,
+		},
+		{
+			name:     "default key",
+			rule:     "API_*=app/api",
+			entry:    importEntry{Key: "API_TOKEN", Value: "x"},
+			wantPath: "app/api",
+			wantKey:  "API_TOKEN",
+			wantOK:   true,
+		},
+		{
+			name:     "path and key of the entry",
+			rule:     "team/*/*=imported/{path}:{1}_{key}",
+			entry:    importEntry{Path: "team/web", Key: "token"},
+			wantPath: "imported/team/web",
+			wantKey:  "web_token",
+			wantOK:   true,
+		},
+		{
+			name:     "empty capture trims slashes",
+			rule:     "*KEY=/apps/{1}/:value",
+			entry:    importEntry{Key: "KEY"},
+			wantPath: "apps",
+			wantKey:  "value",
+			wantOK:   true,
+		},
+		{
+			name:   "regexp characters are literal",
+			rule:   "a.b+*=x",
+			entry:  importEntry{Key: "aXb+c"},
+			wantOK: false,
+		},
+		{
+			name:   "no match",
+			rule:   "DB_*=db",
+			entry:  importEntry{Key: "API_TOKEN"},
+			wantOK: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := parseMapRule(test.rule)
+			if err != nil {
+				t.Fatalf("parseMapRule(%q): %v", test.rule, err)
+			}
+			path, key, ok := rule.apply(test.entry)
+			if ok != test.wantOK || path != test.wantPath || key != test.wantKey {
+				t.Errorf("apply(%+v) = %q, %q, %v, want %q, %q, %v", test.entry, path, key, ok, test.wantPath, test.wantKey, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseMapRuleErrors(t *testing.T) {
+	for _, rule := range []string{"", "=path", "no-equals", "A=path:"} {
+		if _, err := parseMapRule(rule); err == nil {
+			t.Errorf("parseMapRule(%q) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestImportFileType(t *testing.T) {
+	archive := &SecretArchive{
+		Version: secretArchiveVersion,
+		Mount:   "kv",
+		Secrets: []ArchivedSecret{{
+			Path:     "app",
+			Metadata: &ArchivedMetadata{MaxVersions: 5, CustomMetadata: map[string]interface{}{"owner": "team"}},
+			Versions: []ArchivedVersion{{Version: 1, Data: map[string]interface{}{"port": json.Number("8080")}}},
+		}},
+	}
+	jsonArchive, err := marshalArchive(archive, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlArchive, err := marshalArchive(archive, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		content string
+		want    string
+	}{
+		{file: "team.json", content: string(jsonArchive), want: "archive"},
+		{file: "team.yaml", content: string(yamlArchive), want: "archive"},
+		{file: "team.age", content: "age-encryption.org/v1\n", want: "archive"},
+		{file: "keys.yaml", content: "version: 1\napi_key: x\n", want: "yaml"},
+		{file: "keys.yml", content: "version: 1\n", want: "yaml"},
+		{file: "keys.yaml", content: "app:\n  user: admin\n", want: "yaml"},
+		{file: ".env", content: "A=1\n", want: "dotenv"},
+		{file: "prod.env", content: "A=1\n", want: "dotenv"},
+		{file: "app.properties", content: "a=1\n", want: "properties"},
+		{file: "keys.csv", content: "path,key,value\n", want: "csv"},
+	}
+	for _, test := range tests {
+		t.Run(test.file+" "+test.want, func(t *testing.T) {
+			if got := importFileType(test.file, []byte(test.content)); got != test.want {
+				t.Errorf("importFileType(%q) = %q, want %q", test.file, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalArchiveShape(t *testing.T) {
+	for _, content := range []string{
+		`{"version": 1}`,
+		`{"version": 1, "api_key": "x"}`,
+		"version: 1\napi_key: x\n",
+		"version: 1\nsecrets: []\n",
+	} {
+		if _, err := unmarshalArchive([]byte(content)); err == nil {
+			t.Errorf("unmarshalArchive(%q) succeeded, want an error", content)
+		}
+	}
+}
+
+func checkImportEntries(t *testing.T, got []importEntry, err error, want []importEntry, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("error = %v, want %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,390 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// managerItem is an entry of a password manager export: its folders, title,
+// the keys of its secret and the attachments stored as file values.
+type managerItem struct {
+	Folders []string
+	Title   string
+	Fields  map[string]string
+	Files   map[string][]byte
+}
+
+// keepassFields are the standard strings of a KeePass entry and their keys.
+// The TOTP seed is stored under different names by KeePass, KeePassXC and
+// the KeeOtp plugin.
+var keepassFields = map[string]string{
+	"UserName":              "username",
+	"Password":              "password",
+	"URL":                   "url",
+	"Notes":                 "notes",
+	"otp":                   "totp",
+	"TimeOtp-Secret-Base32": "totp",
+	"TOTP Seed":             "totp",
+}
+
+type keepassFile struct {
+	Meta struct {
+		RecycleBinUUID string          `xml:"RecycleBinUUID"`
+		Binaries       []keepassBinary `xml:"Binaries>Binary"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keepassBinary struct {
+	ID         string `xml:"ID,attr"`
+	Ref        string `xml:"Ref,attr"`
+	Compressed bool   `xml:"Compressed,attr"`
+	Content    string `xml:",chardata"`
+}
+
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+type keepassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+	Binaries []struct {
+		Key   string        `xml:"Key"`
+		Value keepassBinary `xml:"Value"`
+	} `xml:"Binary"`
+}
+
+// parseKeePass reads a KeePass 2 XML export. The root group is the database
+// itself, so its subgroups are the top folders. The recycle bin and the
+// history of entries are left out.
+func parseKeePass(content []byte) ([]managerItem, []string, error) {
+	var file keepassFile
+	if err := xml.Unmarshal(content, &file); err != nil {
+		return nil, nil, err
+	}
+	if len(file.Root.Groups) == 0 {
+		return nil, nil, fmt.Errorf("no root group, is this a KeePass XML export?")
+	}
+
+	binaries := map[string][]byte{}
+	for _, binary := range file.Meta.Binaries {
+		decoded, err := decodeKeePassBinary(binary)
+		if err != nil {
+			return nil, nil, fmt.Errorf("attachment %s: %v", binary.ID, err)
+		}
+		binaries[binary.ID] = decoded
+	}
+
+	var items []managerItem
+	var skipped []string
+	var walk func(folders []string, group keepassGroup)
+	walk = func(folders []string, group keepassGroup) {
+		if group.UUID != "" && group.UUID == file.Meta.RecycleBinUUID {
+			for _, entry := range group.Entries {
+				skipped = append(skipped, fmt.Sprintf("'%s': in the recycle bin", keepassTitle(entry)))
+			}
+			return
+		}
+		for _, entry := range group.Entries {
+			item := managerItem{Folders: folders, Title: keepassTitle(entry), Fields: map[string]string{}, Files: map[string][]byte{}}
+			for _, field := range entry.Strings {
+				if field.Key == "Title" || field.Value == "" {
+					continue
+				}
+				key, ok := keepassFields[field.Key]
+				if !ok {
+					key = field.Key
+				}
+				item.Fields[key] = field.Value
+			}
+			for _, binary := range entry.Binaries {
+				if binary.Value.Ref == "" { // attachment stored in the entry
+					decoded, err := decodeKeePassBinary(binary.Value)
+					if err != nil {
+						skipped = append(skipped, fmt.Sprintf("'%s': attachment '%s': %v", item.Title, binary.Key, err))
+						continue
+					}
+					item.Files[binary.Key] = decoded
+					continue
+				}
+				decoded, ok := binaries[binary.Value.Ref]
+				if !ok {
+					skipped = append(skipped, fmt.Sprintf("'%s': attachment '%s' is missing from the export", item.Title, binary.Key))
+					continue
+				}
+				item.Files[binary.Key] = decoded
+			}
+			items = append(items, item)
+		}
+		for _, child := range group.Groups {
+			walk(append(append([]string{}, folders...), child.Name), child)
+		}
+	}
+	for _, root := range file.Root.Groups {
+		walk(nil, root)
+	}
+	return items, skipped, nil
+}
+
+func keepassTitle(entry keepassEntry) string {
+	for _, field := range entry.Strings {
+		if field.Key == "Title" {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+func decodeKeePassBinary(binary keepassBinary) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(binary.Content))
+	if err != nil || !binary.Compressed {
+		return decoded, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+type bitwardenExport struct {
+	Encrypted   bool            `json:"encrypted"`
+	Folders     []bitwardenName `json:"folders"`
+	Collections []bitwardenName `json:"collections"`
+	Items       []struct {
+		FolderID      string   `json:"folderId"`
+		CollectionIDs []string `json:"collectionIds"`
+		Type          int      `json:"type"`
+		Name          string   `json:"name"`
+		Notes         string   `json:"notes"`
+		Fields        []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  int    `json:"type"`
+		} `json:"fields"`
+		Login struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TOTP     string `json:"totp"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+	} `json:"items"`
+}
+
+type bitwardenName struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// bitwardenTypes names the item types that are not imported.
+var bitwardenTypes = map[int]string{3: "card", 4: "identity"}
+
+// parseBitwarden reads an unencrypted Bitwarden JSON export of a vault or
+// an organization. Folder names with slashes are nested folders, and items
+// of an organization are put in the folder of their first collection.
+func parseBitwarden(content []byte) ([]managerItem, []string, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, nil, err
+	}
+	if export.Encrypted {
+		return nil, nil, fmt.Errorf("the export is encrypted, export the vault as unencrypted JSON")
+	}
+	folders := map[string]string{}
+	for _, folder := range append(export.Folders, export.Collections...) {
+		folders[folder.ID] = folder.Name
+	}
+
+	var items []managerItem
+	var skipped []string
+	for _, entry := range export.Items {
+		if kind, ok := bitwardenTypes[entry.Type]; ok {
+			skipped = append(skipped, fmt.Sprintf("'%s': %s items are not imported", entry.Name, kind))
+			continue
+		}
+		folder := folders[entry.FolderID]
+		if folder == "" && len(entry.CollectionIDs) > 0 {
+			folder = folders[entry.CollectionIDs[0]]
+		}
+		item := managerItem{Title: entry.Name, Fields: map[string]string{}}
+		if folder != "" {
+			item.Folders = strings.Split(folder, "/")
+		}
+		for i, uri := range entry.Login.URIs {
+			key := "url"
+			if i > 0 {
+				key = fmt.Sprintf("url_%d", i+1)
+			}
+			item.Fields[key] = uri.URI
+		}
+		item.Fields["username"] = entry.Login.Username
+		item.Fields["password"] = entry.Login.Password
+		item.Fields["totp"] = entry.Login.TOTP
+		item.Fields["notes"] = entry.Notes
+		for _, field := range entry.Fields {
+			if field.Type == 3 { // linked to another field of the item
+				continue
+			}
+			item.Fields[field.Name] = field.Value
+		}
+		items = append(items, item)
+	}
+	return items, skipped, nil
+}
+
+// onePasswordColumns maps the column names of 1Password CSV exports to keys.
+// Other columns are stored under their lower case name.
+var onePasswordColumns = map[string]string{
+	"title":             "title",
+	"name":              "title",
+	"url":               "url",
+	"website":           "url",
+	"username":          "username",
+	"password":          "password",
+	"otpauth":           "totp",
+	"one-time password": "totp",
+	"notes":             "notes",
+	"notesplain":        "notes",
+	"vault":             "folder",
+	"folder":            "folder",
+	"archived":          "archived",
+	"favorite":          "",
+	"tags":              "",
+	"type":              "",
+}
+
+// isOnePasswordCSV reports whether the CSV starts with the header row of a
+// 1Password export.
+func isOnePasswordCSV(content []byte) bool {
+	header, err := csv.NewReader(bytes.NewReader(content)).Read()
+	if err != nil {
+		return false
+	}
+	columns := map[string]bool{}
+	for _, column := range header {
+		columns[onePasswordColumns[strings.ToLower(strings.TrimSpace(column))]] = true
+	}
+	return columns["title"] && columns["password"]
+}
+
+// parseOnePassword reads a 1Password CSV export with a header row. The vault
+// column, when exported, is used as the folder.
+func parseOnePassword(content []byte) ([]managerItem, []string, error) {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("the file is empty")
+	}
+	header := make([]string, len(records[0]))
+	for i, column := range records[0] {
+		name := strings.ToLower(strings.TrimSpace(column))
+		key, ok := onePasswordColumns[name]
+		if !ok {
+			key = name
+		}
+		header[i] = key
+	}
+
+	var items []managerItem
+	var skipped []string
+	for _, record := range records[1:] {
+		item := managerItem{Fields: map[string]string{}}
+		archived := false
+		for i, value := range record {
+			switch header[i] {
+			case "":
+			case "title":
+				item.Title = value
+			case "folder":
+				if value != "" {
+					item.Folders = strings.Split(value, "/")
+				}
+			case "archived":
+				archived = strings.EqualFold(value, "true")
+			default:
+				item.Fields[header[i]] = value
+			}
+		}
+		if archived {
+			skipped = append(skipped, fmt.Sprintf("'%s': archived", item.Title))
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, skipped, nil
+}
+
+// managerEntries turns the items into the keys of one secret per item, at
+// the path of its folders and title. Items without a title or any value are
+// skipped, and items with the same path are numbered.
+func managerEntries(items []managerItem, skipped []string) ([]importEntry, []string) {
+	var entries []importEntry
+	paths := map[string]int{}
+	for _, item := range items {
+		title := pathSegment(item.Title)
+		if title == "" {
+			skipped = append(skipped, "an entry without a title")
+			continue
+		}
+		var entryEntries []importEntry
+		for key, value := range item.Fields {
+			if value != "" {
+				entryEntries = append(entryEntries, importEntry{Key: key, Value: value})
+			}
+		}
+		for name, content := range item.Files {
+			stored, marker := encodeFileValue(content, 0600)
+			entryEntries = append(entryEntries, importEntry{Key: "attachment." + name, Value: stored, File: &marker})
+		}
+		if len(entryEntries) == 0 {
+			skipped = append(skipped, fmt.Sprintf("'%s': no values", item.Title))
+			continue
+		}
+
+		var segments []string
+		for _, folder := range item.Folders {
+			if segment := pathSegment(folder); segment != "" {
+				segments = append(segments, segment)
+			}
+		}
+		secretPath := strings.Join(append(segments, title), "/")
+		paths[secretPath]++
+		if count := paths[secretPath]; count > 1 {
+			secretPath = fmt.Sprintf("%s-%d", secretPath, count)
+			skipped = append(skipped, fmt.Sprintf("'%s': imported as '%s', another entry has the same title", item.Title, secretPath))
+		}
+		for _, entry := range entryEntries {
+			entry.Path = secretPath
+			entries = append(entries, entry)
+		}
+	}
+	return entries, skipped
+}
+
+// pathSegment makes a title or folder name usable as one segment of a path.
+func pathSegment(name string) string {
+	return strings.TrimSpace(strings.ReplaceAll(name, "/", "-"))
+}
@@ -0,0 +1,220 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestParseKeePass(t *testing.T) {
+	items, skipped, err := parseKeePass(readTestdata(t, "keepass.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []managerItem{
+		{
+			Title:  "Router",
+			Fields: map[string]string{"username": "admin", "password": "r0uter"},
+			Files:  map[string][]byte{},
+		},
+		{
+			Folders: []string{"Servers"},
+			Title:   "db/primary",
+			Fields: map[string]string{
+				"password": "s3cret",
+				"totp":     "otpauth://totp/db?secret=JBSWY3DPEHPK3PXP",
+				"Port":     "5432",
+			},
+			Files: map[string][]byte{
+				"id_rsa":   []byte("-----BEGIN KEY-----\nsecret\n-----END KEY-----\n"),
+				"note.txt": []byte("inline attachment"),
+			},
+		},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %#v, want %#v", items, want)
+	}
+
+	wantSkipped := []string{
+		"'db/primary': attachment 'lost.bin' is missing from the export",
+		"'Old Router': in the recycle bin",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %q, want %q", skipped, wantSkipped)
+	}
+}
+
+func TestParseKeePassWithoutRoot(t *testing.T) {
+	if _, _, err := parseKeePass([]byte("<KeePassFile><Root></Root></KeePassFile>")); err == nil {
+		t.Error("parseKeePass succeeded without a root group, want an error")
+	}
+}
+
+func TestParseBitwarden(t *testing.T) {
+	items, skipped, err := parseBitwarden(readTestdata(t, "bitwarden.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []managerItem{
+		{
+			Folders: []string{"Work", "Cloud"},
+			Title:   "AWS",
+			Fields: map[string]string{
+				"url":      "https://aws.amazon.com",
+				"url_2":    "https://console.aws.amazon.com",
+				"username": "root",
+				"password": "hunter2",
+				"totp":     "JBSWY3DPEHPK3PXP",
+				"notes":    "root account",
+				"account":  "123456789012",
+			},
+		},
+		{
+			Title: "AWS",
+			Fields: map[string]string{
+				"username": "",
+				"password": "other",
+				"totp":     "",
+				"notes":    "",
+			},
+		},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %#v, want %#v", items, want)
+	}
+
+	wantSkipped := []string{"'Visa': card items are not imported"}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %q, want %q", skipped, wantSkipped)
+	}
+}
+
+func TestParseBitwardenEncrypted(t *testing.T) {
+	if _, _, err := parseBitwarden([]byte(`{"encrypted": true, "items": []}`)); err == nil {
+		t.Error("parseBitwarden succeeded on an encrypted export, want an error")
+	}
+}
+
+func TestParseOnePassword(t *testing.T) {
+	content := readTestdata(t, "1password.csv")
+	if !isOnePasswordCSV(content) {
+		t.Fatal("isOnePasswordCSV = false, want true")
+	}
+	if isOnePasswordCSV([]byte("path,key,value\napp,user,admin\n")) {
+		t.Error("isOnePasswordCSV = true for a path,key,value file, want false")
+	}
+
+	items, skipped, err := parseOnePassword(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []managerItem{
+		{
+			Folders: []string{"Private"},
+			Title:   "GitHub",
+			Fields: map[string]string{
+				"url":      "https://github.com",
+				"username": "octocat",
+				"password": "gh-pass",
+				"totp":     "",
+				"notes":    "two\nlines",
+			},
+		},
+		{
+			Folders: []string{"Private"},
+			Title:   "GitHub",
+			Fields: map[string]string{
+				"url":      "https://github.com",
+				"username": "work",
+				"password": "work-pass",
+				"totp":     "",
+				"notes":    "",
+			},
+		},
+		{
+			Folders: []string{"Private"},
+			Fields: map[string]string{
+				"url":      "",
+				"username": "",
+				"password": "",
+				"totp":     "",
+				"notes":    "",
+			},
+		},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %#v, want %#v", items, want)
+	}
+
+	wantSkipped := []string{"'Old VPN': archived"}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %q, want %q", skipped, wantSkipped)
+	}
+}
+
+func TestManagerEntries(t *testing.T) {
+	items := []managerItem{
+		{Folders: []string{"Work", " Cloud "}, Title: "AWS", Fields: map[string]string{"password": "a", "notes": ""}},
+		{Folders: []string{"Work", "Cloud"}, Title: "AWS", Fields: map[string]string{"password": "b"}},
+		{Folders: []string{"Work", "Cloud"}, Title: "AWS", Fields: map[string]string{"password": "c"}},
+		{Folders: []string{"a/b", ""}, Title: "db/primary", Files: map[string][]byte{"id_rsa": []byte("key\n")}},
+		{Title: "  ", Fields: map[string]string{"password": "x"}},
+		{Title: "Empty", Fields: map[string]string{"password": ""}},
+	}
+
+	entries, skipped := managerEntries(items, []string{"earlier"})
+
+	secrets := map[string]map[string]interface{}{}
+	files := map[string]*fileMarker{}
+	for _, entry := range entries {
+		if secrets[entry.Path] == nil {
+			secrets[entry.Path] = map[string]interface{}{}
+		}
+		secrets[entry.Path][entry.Key] = entry.Value
+		if entry.File != nil {
+			files[entry.Path+"#"+entry.Key] = entry.File
+		}
+	}
+
+	stored, marker := encodeFileValue([]byte("key\n"), 0600)
+	wantSecrets := map[string]map[string]interface{}{
+		"Work/Cloud/AWS":   {"password": "a"},
+		"Work/Cloud/AWS-2": {"password": "b"},
+		"Work/Cloud/AWS-3": {"password": "c"},
+		"a-b/db-primary":   {"attachment.id_rsa": stored},
+	}
+	if !reflect.DeepEqual(secrets, wantSecrets) {
+		t.Errorf("secrets = %#v, want %#v", secrets, wantSecrets)
+	}
+	wantFiles := map[string]*fileMarker{"a-b/db-primary#attachment.id_rsa": &marker}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("file markers = %#v, want %#v", files, wantFiles)
+	}
+
+	wantSkipped := []string{
+		"earlier",
+		"'AWS': imported as 'Work/Cloud/AWS-2', another entry has the same title",
+		"'AWS': imported as 'Work/Cloud/AWS-3', another entry has the same title",
+		"an entry without a title",
+		"'Empty': no values",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %q, want %q", skipped, wantSkipped)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyForce bool
+	u23      string
+	p23      string
+)
+
+// keyRetries is how many times a key operation is redone when the secret
+// changes between reading and writing it.
+const keyRetries = 3
+
+// keyCmd represents the key command
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Delete, rename and copy single keys of a secret",
+	Long: `
+	Changes single keys inside a secret on a KV version 2 mount without retyping the rest of
+	it. Each change is written as a new version with check-and-set against the version read,
+	so the history stays intact and a concurrent change is never overwritten.
+
+	Examples of the key commands(Keycloak Authentication):
+		$ ./cliapp key delete secret/my-secret old_key other_key
+
+		$ ./cliapp key rename secret/my-secret user username
+
+		$ ./cliapp key copy secret/my-secret password secret/other-secret db_password
+
+	To use Userpass Authentication:
+		$ ./cliapp key delete secret/my-secret old_key --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp key delete secret/my-secret old_key --user=username --pass=password --instance
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(keyCmd)
+
+	addLoginFlags(keyCmd, &u23, &p23)
+}
+
+// modifyKeys reads the current version of a secret, lets change modify a
+// copy of its data and writes it as a new version with check-and-set. When
+// the secret changes in between, the change is applied again to the new
+// version. It returns the version written.
+func modifyKeys(kv *kvMount, secretPath string, change func(data map[string]interface{}) error) (int, error) {
+	requireKVv2(kv, "Key operations")
+	for attempt := 0; ; attempt++ {
+		current, version, err := readForUpdate(kv, secretPath)
+		if err != nil {
+			return 0, err
+		}
+		data := map[string]interface{}{}
+		for key, value := range current {
+			data[key] = value
+		}
+		if err := change(data); err != nil {
+			return 0, err
+		}
+
+		written, err := auth.Client.KVv2(kv.Path).Put(context.Background(), secretPath, data, vault.WithCheckAndSet(version))
+		if isCASError(err) && attempt < keyRetries {
+			continue
+		}
+		if isCASError(err) {
+			casConflict(kv, secretPath, version, err)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to write secret: %v", err)
+		}
+		return written.VersionMetadata.Version, nil
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// keyCopyCmd represents the key copy command
+var keyCopyCmd = &cobra.Command{
+	Use:   "copy <mount/path> <key> <mount/path> [new-key]",
+	Short: "Copy a key from one secret to another",
+	Long: `
+	Copies a key with its value from the current version of one secret to another secret,
+	under the same name or a new one, and writes the destination as a new version. The
+	destination secret is created if it does not exist. An existing key in the destination
+	is only replaced when --force is given. A value written from a file is copied with its
+	recorded encoding, so get --output restores it from the destination too; for binary
+	files this needs the patch capability on the metadata of the destination, granted by
+	the admin policy.
+
+	Example of the key copy command(Keycloak Authentication):
+		$ ./cliapp key copy secret/my-secret password secret/other-secret
+
+		$ ./cliapp key copy secret/my-secret password kv/app db_password --force
+
+	To use Userpass Authentication:
+		$ ./cliapp key copy secret/my-secret password secret/other-secret --user=username --pass=password
+	`,
+	Args: cobra.RangeArgs(3, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u23, p23)
+		source, sourcePath := resolveKVArg(args[0])
+		destination, destinationPath := resolveKVArg(args[2])
+		sourceKey, destinationKey := args[1], args[1]
+		if len(args) == 4 {
+			destinationKey = args[3]
+		}
+		if source.Path == destination.Path && sourcePath == destinationPath && sourceKey == destinationKey {
+			fmt.Println("Error: The source and destination are the same key")
+			os.Exit(1)
+		}
+
+		secret, err := readSecret(source, sourcePath)
+		if err == nil && secret.Data == nil {
+			err = fmt.Errorf("the current version of '%s/%s' is deleted, undelete it first", source.Path, sourcePath)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		value, ok := secret.Data[sourceKey]
+		if !ok {
+			fmt.Printf("Error: No key '%s' in secret '%s/%s'\n", sourceKey, source.Path, sourcePath)
+			os.Exit(1)
+		}
+
+		marker, isFile := fieldFileMarker(secret, sourceKey)
+		isFile = isFile && requireFileSupport(destination, destinationPath, map[string]fileMarker{destinationKey: marker}) != nil
+
+		version, err := modifyKeys(destination, destinationPath, func(data map[string]interface{}) error {
+			if _, exists := data[destinationKey]; exists && !keyForce {
+				return fmt.Errorf("key '%s' already exists in '%s/%s', use --force to replace it", destinationKey, destination.Path, destinationPath)
+			}
+			data[destinationKey] = value
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if isFile { // so that get --output restores the file
+			saveFiles(destination, destinationPath, map[string]fileMarker{destinationKey: marker})
+		}
+
+		fmt.Printf("Copied '%s' to '%s' in '%s/%s', now at version %d.\n", sourceKey, destinationKey, destination.Path, destinationPath, version)
+	},
+}
+
+func init() {
+	keyCmd.AddCommand(keyCopyCmd)
+
+	keyCopyCmd.Flags().BoolVarP(&keyForce, "force", "f", false, "Replace an existing key in the destination")
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// keyDeleteCmd represents the key delete command
+var keyDeleteCmd = &cobra.Command{
+	Use:   "delete <mount/path> <key>...",
+	Short: "Delete keys from a secret",
+	Long: `
+	Deletes one or more keys from a secret and writes the remaining keys as a new version.
+	It is an error if a key does not exist or if no key would be left.
+
+	Example of the key delete command(Keycloak Authentication):
+		$ ./cliapp key delete secret/my-secret old_key other_key
+
+	To use Userpass Authentication:
+		$ ./cliapp key delete secret/my-secret old_key --user=username --pass=password
+	`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u23, p23)
+		kv, secretPath := resolveKVArg(args[0])
+		keys := args[1:]
+
+		version, err := modifyKeys(kv, secretPath, func(data map[string]interface{}) error {
+			for _, key := range keys {
+				if _, ok := data[key]; !ok {
+					return fmt.Errorf("no key '%s' in secret '%s/%s'", key, kv.Path, secretPath)
+				}
+				delete(data, key)
+			}
+			if len(data) == 0 {
+				return fmt.Errorf("no key would be left in '%s/%s', use delete to delete the secret", kv.Path, secretPath)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Deleted %s from '%s/%s', now at version %d.\n", strings.Join(keys, ", "), kv.Path, secretPath, version)
+	},
+}
+
+func init() {
+	keyCmd.AddCommand(keyDeleteCmd)
+}
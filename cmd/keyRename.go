@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// keyRenameCmd represents the key rename command
+var keyRenameCmd = &cobra.Command{
+	Use:   "rename <mount/path> <old-key> <new-key>",
+	Short: "Rename a key of a secret",
+	Long: `
+	Renames a key of a secret, keeping its value, and writes the result as a new version.
+	An existing key with the new name is only replaced when --force is given. A value
+	written from a file keeps its recorded encoding, so get --output still restores it;
+	for binary files this needs the patch capability on the metadata of the secret,
+	granted by the admin policy.
+
+	Example of the key rename command(Keycloak Authentication):
+		$ ./cliapp key rename secret/my-secret user username
+
+		$ ./cliapp key rename secret/my-secret user username --force
+
+	To use Userpass Authentication:
+		$ ./cliapp key rename secret/my-secret user username --user=username --pass=password
+	`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u23, p23)
+		kv, secretPath := resolveKVArg(args[0])
+		oldKey, newKey := args[1], args[2]
+		if oldKey == newKey {
+			fmt.Println("Error: The old and new key names are the same")
+			os.Exit(1)
+		}
+
+		requireKVv2(kv, "Key operations")
+		secret, err := readSecret(kv, secretPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		marker, isFile := fieldFileMarker(secret, oldKey)
+		isFile = isFile && requireFileSupport(kv, secretPath, map[string]fileMarker{newKey: marker}) != nil
+
+		version, err := modifyKeys(kv, secretPath, func(data map[string]interface{}) error {
+			value, ok := data[oldKey]
+			if !ok {
+				return fmt.Errorf("no key '%s' in secret '%s/%s'", oldKey, kv.Path, secretPath)
+			}
+			if _, exists := data[newKey]; exists && !keyForce {
+				return fmt.Errorf("key '%s' already exists in '%s/%s', use --force to replace it", newKey, kv.Path, secretPath)
+			}
+			delete(data, oldKey)
+			data[newKey] = value
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if isFile { // the file encoding moves with the value
+			saveFiles(kv, secretPath, map[string]fileMarker{newKey: marker})
+			if err := removeFileMarkers(kv, secretPath, oldKey); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Renamed '%s' to '%s' in '%s/%s', now at version %d.\n", oldKey, newKey, kv.Path, secretPath, version)
+	},
+}
+
+func init() {
+	keyCmd.AddCommand(keyRenameCmd)
+
+	keyRenameCmd.Flags().BoolVarP(&keyForce, "force", "f", false, "Replace an existing key with the new name")
+}
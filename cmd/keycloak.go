@@ -0,0 +1,244 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"cliapp/auth"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultKeycloakURL   = "http://localhost:8080/auth"
+	defaultKeycloakRealm = "my_realm"
+	keycloakConfigFile   = "keycloak.json"
+)
+
+var (
+	adminPassword            string
+	adminUsername            string
+	keycloakURL              string
+	keycloakRealm            string
+	keycloakClientID         string
+	keycloakClientSecretFile string
+	keycloakConfigPath       string
+)
+
+// KeycloakConfig is the optional keycloak.json file with the Keycloak admin
+// settings. Flags take precedence over environment variables, which take
+// precedence over the file.
+type KeycloakConfig struct {
+	URL          string `json:"url"`
+	Realm        string `json:"realm"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// addKeycloakAdminFlags registers the flags used to authenticate Keycloak
+// admin operations. Either the master realm admin username and password or a
+// confidential client with a service account in the target realm is used.
+func addKeycloakAdminFlags(cmd *cobra.Command, userShorthand, passShorthand string) {
+	cmd.Flags().StringVarP(&adminUsername, "adminUsername", userShorthand, "", "Keycloak Admin Username")
+	cmd.Flags().StringVarP(&adminPassword, "adminPassword", passShorthand, "", "Keycloak Admin Password")
+	cmd.MarkFlagsRequiredTogether("adminUsername", "adminPassword")
+
+	cmd.Flags().StringVar(&keycloakURL, "keycloak-url", "", "Keycloak base URL (env KEYCLOAK_URL, default "+defaultKeycloakURL+")")
+	cmd.Flags().StringVar(&keycloakRealm, "realm", "", "Keycloak realm (env KEYCLOAK_REALM, default "+defaultKeycloakRealm+")")
+	cmd.Flags().StringVar(&keycloakClientID, "client-id", "", "Service account client ID (env KEYCLOAK_CLIENT_ID)")
+	cmd.Flags().StringVar(&keycloakClientSecretFile, "client-secret-file", "", "File containing the service account client secret (env KEYCLOAK_CLIENT_SECRET_FILE)")
+	cmd.Flags().StringVar(&keycloakConfigPath, "keycloak-config", "", "Keycloak config file (env KEYCLOAK_CONFIG, default "+keycloakConfigFile+")")
+}
+
+// loadKeycloakConfig resolves the Keycloak admin settings from the flags,
+// the environment and the config file.
+func loadKeycloakConfig() (KeycloakConfig, error) {
+	config := KeycloakConfig{}
+
+	path := firstNonEmpty(keycloakConfigPath, os.Getenv("KEYCLOAK_CONFIG"))
+	if path != "" || fileExists(keycloakConfigFile) {
+		if path == "" {
+			path = keycloakConfigFile
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("unable to read Keycloak config: %v", err)
+		}
+		if err := json.Unmarshal(content, &config); err != nil {
+			return config, fmt.Errorf("unable to parse Keycloak config '%s': %v", path, err)
+		}
+	}
+
+	config.URL = strings.TrimSuffix(firstNonEmpty(keycloakURL, os.Getenv("KEYCLOAK_URL"), config.URL, defaultKeycloakURL), "/")
+	config.Realm = firstNonEmpty(keycloakRealm, os.Getenv("KEYCLOAK_REALM"), config.Realm, defaultKeycloakRealm)
+	config.ClientID = firstNonEmpty(keycloakClientID, os.Getenv("KEYCLOAK_CLIENT_ID"), config.ClientID)
+
+	secretFile := firstNonEmpty(keycloakClientSecretFile, os.Getenv("KEYCLOAK_CLIENT_SECRET_FILE"))
+	if secretFile != "" {
+		secretBytes, err := ioutil.ReadFile(secretFile)
+		if err != nil {
+			return config, fmt.Errorf("unable to read client secret: %v", err)
+		}
+		config.ClientSecret = strings.TrimSpace(string(secretBytes))
+	} else {
+		config.ClientSecret = firstNonEmpty(os.Getenv("KEYCLOAK_CLIENT_SECRET"), config.ClientSecret)
+	}
+
+	return config, nil
+}
+
+// keycloakToken is an admin API token together with the Keycloak URL and
+// realm it was issued for.
+type keycloakToken struct {
+	*oauth2.Token
+	url   string
+	realm string
+}
+
+// getKeycloakAdminToken loads the Keycloak config and returns a token for the
+// admin API. The admin username and password are used with admin-cli in the
+// master realm when given, otherwise the service account's client
+// credentials grant in the target realm.
+func getKeycloakAdminToken() (*keycloakToken, error) {
+	config, err := loadKeycloakConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var token *oauth2.Token
+	if adminUsername != "" && adminPassword != "" {
+		token, err = auth.GetAdminToken(adminUsername, adminPassword, "admin-cli", "", keycloakTokenURL(config.URL, "master"))
+	} else if config.ClientID == "" || config.ClientSecret == "" {
+		return nil, fmt.Errorf("provide the admin username and password, or a service account client ID and secret")
+	} else {
+		token, err = auth.GetServiceAccountToken(config.ClientID, config.ClientSecret, keycloakTokenURL(config.URL, config.Realm))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &keycloakToken{Token: token, url: config.URL, realm: config.Realm}, nil
+}
+
+// adminURL is the admin API URL of the token's realm.
+func (t *keycloakToken) adminURL() string {
+	return t.url + "/admin/realms/" + t.realm
+}
+
+func keycloakTokenURL(baseURL, realm string) string {
+	return baseURL + "/realms/" + realm + "/protocol/openid-connect/token"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// keycloakCmd represents the keycloak command
+var keycloakCmd = &cobra.Command{
+	Use:   "keycloak",
+	Short: "Inspect and manage the Keycloak realm",
+	Long: `
+	Commands that use the Keycloak admin API of the realm. They authenticate like
+	addKeyCloakUser, with the admin username and password or with a service account.
+
+	Examples of the keycloak commands:
+		$ ./cliapp keycloak sessions --username=greg --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak logout --username=greg --client-id=cliapp-admin --client-secret-file=admin_client_secret.txt
+
+		$ ./cliapp keycloak events --type=LOGIN_ERROR --from=2023-04-01 --format=json --adminUsername=admin --adminPassword=password
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(keycloakCmd)
+}
+
+// keycloakAdminRequest sends a request to the realm's admin API and decodes
+// the JSON response into out, when out is not nil.
+func keycloakAdminRequest(token *keycloakToken, method, path string, body interface{}, out interface{}) error {
+	client := &http.Client{}
+
+	var reader io.Reader
+	if body != nil {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request JSON: %w", err)
+		}
+		reader = bytes.NewBuffer(bodyJSON)
+	}
+
+	req, err := http.NewRequest(method, token.adminURL()+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s failed, status: %d, response: %s", method, path, resp.StatusCode, string(respBody))
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+// getKeycloakUserID looks up a user of the realm by exact username.
+func getKeycloakUserID(token *keycloakToken, username string) (string, error) {
+	var users []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := keycloakAdminRequest(token, "GET", "/users?exact=true&username="+url.QueryEscape(username), nil, &users); err != nil {
+		return "", err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Username, username) {
+			return user.ID, nil
+		}
+	}
+	return "", fmt.Errorf("user not found: %s", username)
+}
+
+// KeycloakClient is a client of the realm as returned by the admin API.
+type KeycloakClient struct {
+	ID       string `json:"id"`
+	ClientID string `json:"clientId"`
+}
@@ -0,0 +1,179 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventsAdmin    bool
+	eventsUsername string
+	eventsTypes    string
+	eventsFrom     string
+	eventsTo       string
+	eventsMax      int
+	eventsJSON     bool
+)
+
+// KeycloakEvent is a login event as returned by the Keycloak admin API.
+type KeycloakEvent struct {
+	Time      int64             `json:"time"`
+	Type      string            `json:"type"`
+	ClientID  string            `json:"clientId"`
+	UserID    string            `json:"userId"`
+	SessionID string            `json:"sessionId"`
+	IPAddress string            `json:"ipAddress"`
+	Error     string            `json:"error,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// KeycloakAdminEvent is an admin event as returned by the Keycloak admin API.
+type KeycloakAdminEvent struct {
+	Time        int64 `json:"time"`
+	AuthDetails struct {
+		ClientID  string `json:"clientId"`
+		UserID    string `json:"userId"`
+		IPAddress string `json:"ipAddress"`
+	} `json:"authDetails"`
+	OperationType  string `json:"operationType"`
+	ResourceType   string `json:"resourceType"`
+	ResourcePath   string `json:"resourcePath"`
+	Representation string `json:"representation,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// keycloakEventsCmd represents the keycloak events command
+var keycloakEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Query the realm's login and admin events",
+	Long: `
+	Queries the events recorded by the realm, newest first. Login events can be filtered by
+	user, by event type (e.g. LOGIN, LOGIN_ERROR, LOGOUT, CODE_TO_TOKEN) and by a date range
+	in the form YYYY-MM-DD. With --admin the admin events are queried instead, and --type
+	filters on the operation type (CREATE, UPDATE, DELETE, ACTION). Events must be enabled
+	in the realm's event settings to be recorded.
+
+	Examples of the keycloak events command:
+		$ ./cliapp keycloak events --username=greg --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak events --type=LOGIN,LOGIN_ERROR --from=2023-04-01 --to=2023-04-30 --format=json --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak events --admin --type=DELETE --max=20 --adminUsername=admin --adminPassword=password
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, date := range []string{eventsFrom, eventsTo} {
+			if date == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				fmt.Println("Error: Dates must be given as YYYY-MM-DD")
+				os.Exit(1)
+			}
+		}
+		if eventsMax < 1 {
+			fmt.Println("Error: The maximum number of events must be at least one")
+			os.Exit(1)
+		}
+
+		if eventsJSON {
+			outputFormat = "json"
+		}
+
+		token, err := getKeycloakAdminToken()
+		if err != nil {
+			log.Fatalf("Error getting Keycloak token: %v", err)
+		}
+
+		query := url.Values{}
+		query.Set("max", strconv.Itoa(eventsMax))
+		if eventsFrom != "" {
+			query.Set("dateFrom", eventsFrom)
+		}
+		if eventsTo != "" {
+			query.Set("dateTo", eventsTo)
+		}
+
+		var userID string
+		if eventsUsername != "" {
+			if userID, err = getKeycloakUserID(token, eventsUsername); err != nil {
+				log.Fatalf("Error fetching Keycloak user: %v", err)
+			}
+		}
+
+		if eventsAdmin {
+			if userID != "" {
+				query.Set("authUser", userID)
+			}
+			for _, operation := range splitList(eventsTypes) {
+				query.Add("operationTypes", operation)
+			}
+
+			var events []KeycloakAdminEvent
+			if err := keycloakAdminRequest(token, "GET", "/admin-events?"+query.Encode(), nil, &events); err != nil {
+				log.Fatalf("Error fetching admin events: %v", err)
+			}
+
+			printFormatted(events, func() {
+				for _, event := range events {
+					fmt.Printf("%s %s %s %s by user %s from %s", formatMillis(event.Time), event.OperationType, event.ResourceType,
+						event.ResourcePath, event.AuthDetails.UserID, event.AuthDetails.IPAddress)
+					if event.Error != "" {
+						fmt.Printf(" error: %s", event.Error)
+					}
+					fmt.Println()
+				}
+				fmt.Printf("%d admin events.\n", len(events))
+			})
+			return
+		}
+
+		if userID != "" {
+			query.Set("user", userID)
+		}
+		for _, eventType := range splitList(eventsTypes) {
+			query.Add("type", eventType)
+		}
+
+		var events []KeycloakEvent
+		if err := keycloakAdminRequest(token, "GET", "/events?"+query.Encode(), nil, &events); err != nil {
+			log.Fatalf("Error fetching events: %v", err)
+		}
+
+		printFormatted(events, func() {
+			for _, event := range events {
+				fmt.Printf("%s %s client %s user %s from %s", formatMillis(event.Time), event.Type, event.ClientID,
+					event.UserID, event.IPAddress)
+				if event.Error != "" {
+					fmt.Printf(" error: %s", event.Error)
+				}
+				fmt.Println()
+			}
+			fmt.Printf("%d events.\n", len(events))
+		})
+	},
+}
+
+func init() {
+	keycloakCmd.AddCommand(keycloakEventsCmd)
+
+	keycloakEventsCmd.Flags().BoolVar(&eventsAdmin, "admin", false, "Query admin events instead of login events")
+	keycloakEventsCmd.Flags().StringVarP(&eventsUsername, "username", "s", "", "Only events of this user")
+	keycloakEventsCmd.Flags().StringVarP(&eventsTypes, "type", "t", "", "Comma separated event or operation types")
+	keycloakEventsCmd.Flags().StringVarP(&eventsFrom, "from", "f", "", "First day, YYYY-MM-DD")
+	keycloakEventsCmd.Flags().StringVar(&eventsTo, "to", "", "Last day, YYYY-MM-DD")
+	keycloakEventsCmd.Flags().IntVarP(&eventsMax, "max", "n", 100, "Maximum number of events")
+	keycloakEventsCmd.Flags().BoolVarP(&eventsJSON, "json", "j", false, "Print the events as JSON")
+	if err := keycloakEventsCmd.Flags().MarkDeprecated("json", "use --format=json"); err != nil {
+		fmt.Println(err)
+	}
+	addKeycloakAdminFlags(keycloakEventsCmd, "u", "p")
+}
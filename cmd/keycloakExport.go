@@ -0,0 +1,300 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// realmExportVersion is the format version of the export file. Import
+// refuses files with a newer version.
+const realmExportVersion = 1
+
+var (
+	exportFile           string
+	exportUsers          bool
+	exportIncludeSecrets bool
+	exportIncludeBuiltin bool
+)
+
+// builtinClients are created by Keycloak in every realm and are left out of
+// exports unless --include-builtin is given.
+var builtinClients = map[string]bool{
+	"account":                true,
+	"account-console":        true,
+	"admin-cli":              true,
+	"broker":                 true,
+	"realm-management":       true,
+	"security-admin-console": true,
+}
+
+// RealmExport is the file written by keycloak export and read by keycloak
+// import. Objects are kept as the admin API represents them, without their
+// internal IDs, so that they can be matched by name in another realm.
+type RealmExport struct {
+	Version     int                                 `json:"version" yaml:"version"`
+	Realm       string                              `json:"realm" yaml:"realm"`
+	ExportedAt  string                              `json:"exported_at" yaml:"exported_at"`
+	Roles       []map[string]interface{}            `json:"roles" yaml:"roles"`
+	Clients     []map[string]interface{}            `json:"clients" yaml:"clients"`
+	ClientRoles map[string][]map[string]interface{} `json:"client_roles" yaml:"client_roles"`
+	Groups      []map[string]interface{}            `json:"groups" yaml:"groups"`
+	Users       []map[string]interface{}            `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+// keycloakExportCmd represents the keycloak export command
+var keycloakExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the realm's clients, groups, roles and mappers to a file",
+	Long: `
+	Exports the realm's configuration to a versioned JSON or YAML file, chosen by the file
+	extension: realm roles, clients with their protocol mappers and client roles, the roles
+	that make up each composite role, and the group tree with attributes and role mappings.
+	Users and their group memberships are exported with --users, without credentials.
+	Client secrets are left out unless --include-secrets is given, and Keycloak's built-in
+	clients unless --include-builtin. The file can be restored into a new environment with
+	keycloak import.
+
+	Examples of the keycloak export command:
+		$ ./cliapp keycloak export --file=realm.json --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak export --file=realm.yaml --users --client-id=cliapp-admin --client-secret-file=admin_client_secret.txt
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := getKeycloakAdminToken()
+		if err != nil {
+			log.Fatalf("Error getting Keycloak token: %v", err)
+		}
+
+		export, err := exportRealm(token)
+		if err != nil {
+			log.Fatalf("Error exporting realm: %v", err)
+		}
+
+		content, err := marshalRealmExport(export, exportFile)
+		if err != nil {
+			log.Fatalf("Error encoding export: %v", err)
+		}
+		if err := ioutil.WriteFile(exportFile, content, 0600); err != nil {
+			log.Fatalf("Error writing export to file: %v", err)
+		}
+
+		fmt.Printf("Exported %d roles, %d clients, %d groups and %d users of realm '%s' to: %s\n",
+			len(export.Roles), len(export.Clients), countGroups(export.Groups), len(export.Users), export.Realm, exportFile)
+	},
+}
+
+func init() {
+	keycloakCmd.AddCommand(keycloakExportCmd)
+
+	keycloakExportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "File to export to, .json or .yaml")
+	if err := keycloakExportCmd.MarkFlagRequired("file"); err != nil {
+		fmt.Println(err)
+	}
+	keycloakExportCmd.Flags().BoolVar(&exportUsers, "users", false, "Include users and their group memberships")
+	keycloakExportCmd.Flags().BoolVar(&exportIncludeSecrets, "include-secrets", false, "Include client secrets")
+	keycloakExportCmd.Flags().BoolVar(&exportIncludeBuiltin, "include-builtin", false, "Include Keycloak's built-in clients")
+	addKeycloakAdminFlags(keycloakExportCmd, "u", "p")
+}
+
+func exportRealm(token *keycloakToken) (*RealmExport, error) {
+	export := &RealmExport{
+		Version:     realmExportVersion,
+		Realm:       token.realm,
+		ExportedAt:  time.Now().UTC().Format(time.RFC3339),
+		ClientRoles: map[string][]map[string]interface{}{},
+	}
+
+	if err := keycloakAdminRequest(token, "GET", "/roles?briefRepresentation=false", nil, &export.Roles); err != nil {
+		return nil, err
+	}
+
+	var clients []map[string]interface{}
+	if err := keycloakAdminRequest(token, "GET", "/clients", nil, &clients); err != nil {
+		return nil, err
+	}
+	clientIDs := map[string]string{}
+	for _, client := range clients {
+		clientIDs[fmt.Sprint(client["id"])] = fmt.Sprint(client["clientId"])
+	}
+	if err := exportComposites(token, export.Roles, clientIDs); err != nil {
+		return nil, err
+	}
+
+	for _, client := range clients {
+		clientID := fmt.Sprint(client["clientId"])
+		if builtinClients[clientID] && !exportIncludeBuiltin {
+			continue
+		}
+
+		var roles []map[string]interface{}
+		if err := keycloakAdminRequest(token, "GET", "/clients/"+fmt.Sprint(client["id"])+"/roles?briefRepresentation=false", nil, &roles); err != nil {
+			return nil, err
+		}
+		if err := exportComposites(token, roles, clientIDs); err != nil {
+			return nil, err
+		}
+		if len(roles) > 0 {
+			export.ClientRoles[clientID] = stripIDs(roles)
+		}
+
+		if !exportIncludeSecrets {
+			delete(client, "secret")
+		}
+		delete(client, "access")
+		export.Clients = append(export.Clients, client)
+	}
+
+	if err := keycloakAdminRequest(token, "GET", "/groups?briefRepresentation=false", nil, &export.Groups); err != nil {
+		return nil, err
+	}
+
+	if exportUsers {
+		for first := 0; ; first += 100 {
+			var users []map[string]interface{}
+			if err := keycloakAdminRequest(token, "GET", "/users?briefRepresentation=false&max=100&first="+strconv.Itoa(first), nil, &users); err != nil {
+				return nil, err
+			}
+			for _, user := range users {
+				var groups []KeycloakGroup
+				if err := keycloakAdminRequest(token, "GET", "/users/"+url.PathEscape(fmt.Sprint(user["id"]))+"/groups", nil, &groups); err != nil {
+					return nil, err
+				}
+				var paths []string
+				for _, group := range groups {
+					paths = append(paths, group.Path)
+				}
+				user["groups"] = paths
+				delete(user, "access")
+				export.Users = append(export.Users, user)
+			}
+			if len(users) < 100 {
+				break
+			}
+		}
+	}
+
+	export.Roles = stripIDs(export.Roles)
+	export.Clients = stripIDs(export.Clients)
+	export.Groups = stripIDs(export.Groups)
+	export.Users = stripIDs(export.Users)
+	return export, nil
+}
+
+// exportComposites adds the roles that make up each composite role, in the
+// admin API's representation: realm role names under "realm" and client role
+// names by client ID under "client". clientIDs maps client UUIDs to client IDs.
+func exportComposites(token *keycloakToken, roles []map[string]interface{}, clientIDs map[string]string) error {
+	for _, role := range roles {
+		if composite, _ := role["composite"].(bool); !composite {
+			continue
+		}
+
+		var children []map[string]interface{}
+		if err := keycloakAdminRequest(token, "GET", "/roles-by-id/"+url.PathEscape(fmt.Sprint(role["id"]))+"/composites", nil, &children); err != nil {
+			return err
+		}
+
+		composites := map[string]interface{}{}
+		var realm []string
+		client := map[string][]string{}
+		for _, child := range children {
+			name := fmt.Sprint(child["name"])
+			if clientRole, _ := child["clientRole"].(bool); clientRole {
+				clientID := clientIDs[fmt.Sprint(child["containerId"])]
+				client[clientID] = append(client[clientID], name)
+			} else {
+				realm = append(realm, name)
+			}
+		}
+		if len(realm) > 0 {
+			composites["realm"] = realm
+		}
+		if len(client) > 0 {
+			composites["client"] = client
+		}
+		role["composites"] = composites
+	}
+	return nil
+}
+
+// stripIDs removes Keycloak's internal IDs from the objects, recursively.
+func stripIDs(objects []map[string]interface{}) []map[string]interface{} {
+	for _, object := range objects {
+		stripValueIDs(object)
+	}
+	return objects
+}
+
+func stripValueIDs(value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		delete(value, "id")
+		delete(value, "containerId")
+		for _, child := range value {
+			stripValueIDs(child)
+		}
+	case []interface{}:
+		for _, child := range value {
+			stripValueIDs(child)
+		}
+	}
+}
+
+func countGroups(groups []map[string]interface{}) int {
+	count := 0
+	for _, group := range groups {
+		count++
+		count += countGroups(toObjects(group["subGroups"]))
+	}
+	return count
+}
+
+// toObjects converts a decoded JSON or YAML list of objects.
+func toObjects(value interface{}) []map[string]interface{} {
+	var objects []map[string]interface{}
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if object, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, object)
+			}
+		}
+	}
+	return objects
+}
+
+func isYAMLFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func marshalRealmExport(export *RealmExport, file string) ([]byte, error) {
+	if isYAMLFile(file) {
+		return yaml.Marshal(export)
+	}
+	return json.MarshalIndent(export, "", "  ")
+}
+
+func unmarshalRealmExport(content []byte, file string) (*RealmExport, error) {
+	export := &RealmExport{}
+	if isYAMLFile(file) {
+		if err := yaml.Unmarshal(content, export); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(content, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
@@ -0,0 +1,709 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFile   string
+	importDryRun bool
+)
+
+// importAction is one change of a realm import. The description is printed
+// in the preview and apply makes the change.
+type importAction struct {
+	description string
+	apply       func() error
+}
+
+// keycloakImportCmd represents the keycloak import command
+var keycloakImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Reconcile the realm from an export file",
+	Long: `
+	Reconciles the realm with a file written by keycloak export. Roles, clients, protocol
+	mappers, groups and users are matched by name; missing ones are created and changed
+	fields are updated. The roles of composite roles are added once all roles exist.
+	Nothing is deleted. The changes are previewed first, field by field,
+	and with --dry-run only the preview is shown. Users are created without credentials.
+
+	Examples of the keycloak import command:
+		$ ./cliapp keycloak import --file=realm.json --dry-run --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak import --file=realm.yaml --realm=my_realm --client-id=cliapp-admin --client-secret-file=admin_client_secret.txt
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		content, err := ioutil.ReadFile(importFile)
+		if err != nil {
+			log.Fatalf("Error reading export file: %v", err)
+		}
+		export, err := unmarshalRealmExport(content, importFile)
+		if err != nil {
+			log.Fatalf("Error parsing export file: %v", err)
+		}
+		if export.Version < 1 || export.Version > realmExportVersion {
+			fmt.Printf("Error: Unsupported export file version %d\n", export.Version)
+			os.Exit(1)
+		}
+
+		token, err := getKeycloakAdminToken()
+		if err != nil {
+			log.Fatalf("Error getting Keycloak token: %v", err)
+		}
+		if export.Realm != token.realm {
+			fmt.Printf("Importing realm '%s' into realm '%s'.\n", export.Realm, token.realm)
+		}
+
+		actions, err := planRealmImport(token, export)
+		if err != nil {
+			log.Fatalf("Error comparing realm: %v", err)
+		}
+
+		if len(actions) == 0 {
+			fmt.Println("Realm is up to date.")
+			return
+		}
+		for _, action := range actions {
+			fmt.Println(action.description)
+		}
+		if importDryRun {
+			fmt.Printf("%d changes to apply (dry run).\n", len(actions))
+			return
+		}
+
+		for _, action := range actions {
+			if err := action.apply(); err != nil {
+				log.Fatalf("Error applying '%s': %v", action.description, err)
+			}
+		}
+		fmt.Printf("%d changes applied.\n", len(actions))
+	},
+}
+
+func init() {
+	keycloakCmd.AddCommand(keycloakImportCmd)
+
+	keycloakImportCmd.Flags().StringVarP(&importFile, "file", "f", "", "Export file to import, .json or .yaml")
+	if err := keycloakImportCmd.MarkFlagRequired("file"); err != nil {
+		fmt.Println(err)
+	}
+	keycloakImportCmd.Flags().BoolVarP(&importDryRun, "dry-run", "d", false, "Only preview the changes")
+	addKeycloakAdminFlags(keycloakImportCmd, "u", "p")
+}
+
+func planRealmImport(token *keycloakToken, export *RealmExport) ([]importAction, error) {
+	var actions []importAction
+
+	roleActions, err := planRoles(token, "/roles", "role", export.Roles)
+	if err != nil {
+		return nil, err
+	}
+	actions = append(actions, roleActions...)
+
+	var clients []map[string]interface{}
+	if err := keycloakAdminRequest(token, "GET", "/clients", nil, &clients); err != nil {
+		return nil, err
+	}
+	current := map[string]map[string]interface{}{}
+	for _, client := range clients {
+		current[fmt.Sprint(client["clientId"])] = client
+	}
+
+	for _, desired := range export.Clients {
+		desired := desired
+		clientID := fmt.Sprint(desired["clientId"])
+		existing, ok := current[clientID]
+		if !ok {
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("+ create client '%s'", clientID),
+				apply: func() error {
+					return keycloakAdminRequest(token, "POST", "/clients", desired, nil)
+				},
+			})
+			for _, role := range export.ClientRoles[clientID] {
+				role := mergeFields(nil, role, "composite", "composites")
+				actions = append(actions, importAction{
+					description: fmt.Sprintf("+ create role '%v' of client '%s'", role["name"], clientID),
+					apply: func() error {
+						id, err := keycloakClientUUID(token, clientID)
+						if err != nil {
+							return err
+						}
+						return keycloakAdminRequest(token, "POST", "/clients/"+id+"/roles", role, nil)
+					},
+				})
+			}
+			continue
+		}
+
+		id := fmt.Sprint(existing["id"])
+		if changes := diffFields(desired, existing, "protocolMappers"); len(changes) > 0 {
+			merged := mergeFields(existing, desired, "protocolMappers")
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("~ update client '%s':\n%s", clientID, strings.Join(changes, "\n")),
+				apply: func() error {
+					return keycloakAdminRequest(token, "PUT", "/clients/"+id, merged, nil)
+				},
+			})
+		}
+
+		mappers := map[string]map[string]interface{}{}
+		for _, mapper := range toObjects(existing["protocolMappers"]) {
+			mappers[fmt.Sprint(mapper["name"])] = mapper
+		}
+		for _, mapper := range toObjects(desired["protocolMappers"]) {
+			mapper := mapper
+			name := fmt.Sprint(mapper["name"])
+			existingMapper, ok := mappers[name]
+			if !ok {
+				actions = append(actions, importAction{
+					description: fmt.Sprintf("+ create mapper '%s' of client '%s'", name, clientID),
+					apply: func() error {
+						return keycloakAdminRequest(token, "POST", "/clients/"+id+"/protocol-mappers/models", mapper, nil)
+					},
+				})
+				continue
+			}
+			if changes := diffFields(mapper, existingMapper); len(changes) > 0 {
+				merged := mergeFields(existingMapper, mapper)
+				actions = append(actions, importAction{
+					description: fmt.Sprintf("~ update mapper '%s' of client '%s':\n%s", name, clientID, strings.Join(changes, "\n")),
+					apply: func() error {
+						return keycloakAdminRequest(token, "PUT", "/clients/"+id+"/protocol-mappers/models/"+fmt.Sprint(existingMapper["id"]), merged, nil)
+					},
+				})
+			}
+		}
+
+		clientRoleActions, err := planRoles(token, "/clients/"+id+"/roles", "role of client '"+clientID+"'", export.ClientRoles[clientID])
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, clientRoleActions...)
+	}
+
+	compositeActions, err := planComposites(token, export)
+	if err != nil {
+		return nil, err
+	}
+	actions = append(actions, compositeActions...)
+
+	groupActions, err := planGroups(token, export.Groups)
+	if err != nil {
+		return nil, err
+	}
+	actions = append(actions, groupActions...)
+
+	userActions, err := planUsers(token, export.Users)
+	if err != nil {
+		return nil, err
+	}
+	actions = append(actions, userActions...)
+
+	return actions, nil
+}
+
+func planRoles(token *keycloakToken, path, kind string, roles []map[string]interface{}) ([]importAction, error) {
+	if len(roles) == 0 {
+		return nil, nil
+	}
+
+	var existing []map[string]interface{}
+	if err := keycloakAdminRequest(token, "GET", path+"?briefRepresentation=false", nil, &existing); err != nil {
+		return nil, err
+	}
+	current := map[string]map[string]interface{}{}
+	for _, role := range existing {
+		current[fmt.Sprint(role["name"])] = role
+	}
+
+	var actions []importAction
+	for _, role := range roles {
+		role := role
+		name := fmt.Sprint(role["name"])
+		existingRole, ok := current[name]
+		if !ok {
+			created := mergeFields(nil, role, "composite", "composites")
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("+ create %s '%s'", kind, name),
+				apply: func() error {
+					return keycloakAdminRequest(token, "POST", path, created, nil)
+				},
+			})
+			continue
+		}
+		if changes := diffFields(role, existingRole, "composite", "composites"); len(changes) > 0 {
+			merged := mergeFields(existingRole, role, "composite", "composites")
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("~ update %s '%s':\n%s", kind, name, strings.Join(changes, "\n")),
+				apply: func() error {
+					return keycloakAdminRequest(token, "PUT", path+"/"+url.PathEscape(name), merged, nil)
+				},
+			})
+		}
+	}
+	return actions, nil
+}
+
+// roleRef names a realm role, or a client role when client is set.
+type roleRef struct {
+	client string
+	name   string
+}
+
+func (r roleRef) String() string {
+	if r.client == "" {
+		return r.name
+	}
+	return r.client + "/" + r.name
+}
+
+// compositeRefs reads the roles of an exported composite role.
+func compositeRefs(composites interface{}) []roleRef {
+	object, ok := composites.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var refs []roleRef
+	if names, ok := object["realm"].([]interface{}); ok {
+		for _, name := range names {
+			refs = append(refs, roleRef{name: fmt.Sprint(name)})
+		}
+	}
+	if clients, ok := object["client"].(map[string]interface{}); ok {
+		for client, names := range clients {
+			if names, ok := names.([]interface{}); ok {
+				for _, name := range names {
+					refs = append(refs, roleRef{client: client, name: fmt.Sprint(name)})
+				}
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+	return refs
+}
+
+// planComposites adds the missing roles of each composite role. They are
+// applied after all roles and clients are created, as a composite may refer
+// to roles defined later in the file.
+func planComposites(token *keycloakToken, export *RealmExport) ([]importAction, error) {
+	var clients []KeycloakClient
+	if err := keycloakAdminRequest(token, "GET", "/clients", nil, &clients); err != nil {
+		return nil, err
+	}
+	clientUUIDs := map[string]string{}
+	clientIDs := map[string]string{}
+	for _, client := range clients {
+		clientUUIDs[client.ClientID] = client.ID
+		clientIDs[client.ID] = client.ClientID
+	}
+
+	var actions []importAction
+	plan := func(client string, roles []map[string]interface{}) error {
+		var existing map[string]string
+		for _, role := range roles {
+			refs := compositeRefs(role["composites"])
+			if len(refs) == 0 {
+				continue
+			}
+			if existing == nil {
+				var err error
+				if existing, err = keycloakRoleIDs(token, client, clientUUIDs); err != nil {
+					return err
+				}
+			}
+
+			parent := roleRef{client: client, name: fmt.Sprint(role["name"])}
+			missing := refs
+			if id, ok := existing[parent.name]; ok {
+				var children []map[string]interface{}
+				if err := keycloakAdminRequest(token, "GET", "/roles-by-id/"+url.PathEscape(id)+"/composites", nil, &children); err != nil {
+					return err
+				}
+				present := map[roleRef]bool{}
+				for _, child := range children {
+					ref := roleRef{name: fmt.Sprint(child["name"])}
+					if clientRole, _ := child["clientRole"].(bool); clientRole {
+						ref.client = clientIDs[fmt.Sprint(child["containerId"])]
+					}
+					present[ref] = true
+				}
+				missing = nil
+				for _, ref := range refs {
+					if !present[ref] {
+						missing = append(missing, ref)
+					}
+				}
+			}
+			if len(missing) == 0 {
+				continue
+			}
+
+			var names []string
+			for _, ref := range missing {
+				names = append(names, ref.String())
+			}
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("+ add composites %s to role '%s'", strings.Join(names, ", "), parent),
+				apply: func() error {
+					target, err := keycloakRole(token, parent)
+					if err != nil {
+						return err
+					}
+					var children []map[string]interface{}
+					for _, ref := range missing {
+						child, err := keycloakRole(token, ref)
+						if err != nil {
+							return err
+						}
+						children = append(children, child)
+					}
+					return keycloakAdminRequest(token, "POST", "/roles-by-id/"+url.PathEscape(fmt.Sprint(target["id"]))+"/composites", children, nil)
+				},
+			})
+		}
+		return nil
+	}
+
+	if err := plan("", export.Roles); err != nil {
+		return nil, err
+	}
+	var names []string
+	for clientID := range export.ClientRoles {
+		names = append(names, clientID)
+	}
+	sort.Strings(names)
+	for _, clientID := range names {
+		if err := plan(clientID, export.ClientRoles[clientID]); err != nil {
+			return nil, err
+		}
+	}
+	return actions, nil
+}
+
+// keycloakRoleIDs returns the IDs of the realm roles, or of the roles of the
+// client, by name. A client that does not exist yet has no roles.
+func keycloakRoleIDs(token *keycloakToken, client string, clientUUIDs map[string]string) (map[string]string, error) {
+	path := "/roles"
+	if client != "" {
+		uuid, ok := clientUUIDs[client]
+		if !ok {
+			return map[string]string{}, nil
+		}
+		path = "/clients/" + uuid + "/roles"
+	}
+
+	var roles []map[string]interface{}
+	if err := keycloakAdminRequest(token, "GET", path, nil, &roles); err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, role := range roles {
+		ids[fmt.Sprint(role["name"])] = fmt.Sprint(role["id"])
+	}
+	return ids, nil
+}
+
+func keycloakRole(token *keycloakToken, ref roleRef) (map[string]interface{}, error) {
+	path := "/roles/" + url.PathEscape(ref.name)
+	if ref.client != "" {
+		id, err := keycloakClientUUID(token, ref.client)
+		if err != nil {
+			return nil, err
+		}
+		path = "/clients/" + id + "/roles/" + url.PathEscape(ref.name)
+	}
+
+	var role map[string]interface{}
+	if err := keycloakAdminRequest(token, "GET", path, nil, &role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func planGroups(token *keycloakToken, groups []map[string]interface{}) ([]importAction, error) {
+	current, err := keycloakGroupsByPath(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []importAction
+	var walk func(groups []map[string]interface{}, parent string)
+	walk = func(groups []map[string]interface{}, parent string) {
+		for _, group := range groups {
+			group := group
+			name := fmt.Sprint(group["name"])
+			path := parent + "/" + name
+			existing, ok := current[path]
+
+			if !ok {
+				parentPath := parent
+				actions = append(actions, importAction{
+					description: fmt.Sprintf("+ create group '%s'", path),
+					apply: func() error {
+						body := map[string]interface{}{"name": name, "attributes": group["attributes"]}
+						if parentPath == "" {
+							return keycloakAdminRequest(token, "POST", "/groups", body, nil)
+						}
+						parentID, err := keycloakGroupID(token, parentPath)
+						if err != nil {
+							return err
+						}
+						return keycloakAdminRequest(token, "POST", "/groups/"+parentID+"/children", body, nil)
+					},
+				})
+			} else if changes := diffFields(group, existing, "subGroups", "realmRoles", "clientRoles", "path"); len(changes) > 0 {
+				actions = append(actions, importAction{
+					description: fmt.Sprintf("~ update group '%s':\n%s", path, strings.Join(changes, "\n")),
+					apply: func() error {
+						id, err := keycloakGroupID(token, path)
+						if err != nil {
+							return err
+						}
+						return keycloakAdminRequest(token, "PUT", "/groups/"+id, map[string]interface{}{"name": name, "attributes": group["attributes"]}, nil)
+					},
+				})
+			}
+
+			for _, role := range missingItems(toStrings(group["realmRoles"]), toStrings(existing["realmRoles"])) {
+				role := role
+				actions = append(actions, importAction{
+					description: fmt.Sprintf("+ map realm role '%s' to group '%s'", role, path),
+					apply: func() error {
+						id, err := keycloakGroupID(token, path)
+						if err != nil {
+							return err
+						}
+						var representation map[string]interface{}
+						if err := keycloakAdminRequest(token, "GET", "/roles/"+url.PathEscape(role), nil, &representation); err != nil {
+							return err
+						}
+						return keycloakAdminRequest(token, "POST", "/groups/"+id+"/role-mappings/realm", []interface{}{representation}, nil)
+					},
+				})
+			}
+
+			desiredClientRoles, _ := group["clientRoles"].(map[string]interface{})
+			existingClientRoles, _ := existing["clientRoles"].(map[string]interface{})
+			for clientID, roles := range desiredClientRoles {
+				for _, role := range missingItems(toStrings(roles), toStrings(existingClientRoles[clientID])) {
+					clientID, role := clientID, role
+					actions = append(actions, importAction{
+						description: fmt.Sprintf("+ map role '%s' of client '%s' to group '%s'", role, clientID, path),
+						apply: func() error {
+							id, err := keycloakGroupID(token, path)
+							if err != nil {
+								return err
+							}
+							clientUUID, err := keycloakClientUUID(token, clientID)
+							if err != nil {
+								return err
+							}
+							var representation map[string]interface{}
+							if err := keycloakAdminRequest(token, "GET", "/clients/"+clientUUID+"/roles/"+url.PathEscape(role), nil, &representation); err != nil {
+								return err
+							}
+							return keycloakAdminRequest(token, "POST", "/groups/"+id+"/role-mappings/clients/"+clientUUID, []interface{}{representation}, nil)
+						},
+					})
+				}
+			}
+
+			walk(toObjects(group["subGroups"]), path)
+		}
+	}
+	walk(groups, "")
+
+	return actions, nil
+}
+
+func planUsers(token *keycloakToken, users []map[string]interface{}) ([]importAction, error) {
+	var actions []importAction
+	for _, user := range users {
+		user := user
+		username := fmt.Sprint(user["username"])
+
+		var existing []map[string]interface{}
+		if err := keycloakAdminRequest(token, "GET", "/users?briefRepresentation=false&exact=true&username="+url.QueryEscape(username), nil, &existing); err != nil {
+			return nil, err
+		}
+		groups := toStrings(user["groups"])
+
+		if len(existing) == 0 {
+			body := mergeFields(map[string]interface{}{}, user, "credentials")
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("+ create user '%s' in groups %v", username, groups),
+				apply: func() error {
+					return keycloakAdminRequest(token, "POST", "/users", body, nil)
+				},
+			})
+			continue
+		}
+
+		current := existing[0]
+		id := fmt.Sprint(current["id"])
+		if changes := diffFields(user, current, "groups", "credentials", "createdTimestamp", "totp", "notBefore"); len(changes) > 0 {
+			merged := mergeFields(current, user, "groups", "credentials")
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("~ update user '%s':\n%s", username, strings.Join(changes, "\n")),
+				apply: func() error {
+					return keycloakAdminRequest(token, "PUT", "/users/"+id, merged, nil)
+				},
+			})
+		}
+
+		var memberships []KeycloakGroup
+		if err := keycloakAdminRequest(token, "GET", "/users/"+id+"/groups", nil, &memberships); err != nil {
+			return nil, err
+		}
+		var currentPaths []string
+		for _, group := range memberships {
+			currentPaths = append(currentPaths, group.Path)
+		}
+		for _, path := range missingItems(groups, currentPaths) {
+			path := path
+			actions = append(actions, importAction{
+				description: fmt.Sprintf("+ add user '%s' to group '%s'", username, path),
+				apply: func() error {
+					groupID, err := keycloakGroupID(token, path)
+					if err != nil {
+						return err
+					}
+					return keycloakAdminRequest(token, "PUT", "/users/"+id+"/groups/"+groupID, nil, nil)
+				},
+			})
+		}
+	}
+	return actions, nil
+}
+
+// keycloakGroupsByPath returns the full representation of every group,
+// keyed by its path.
+func keycloakGroupsByPath(token *keycloakToken) (map[string]map[string]interface{}, error) {
+	var groups []map[string]interface{}
+	if err := keycloakAdminRequest(token, "GET", "/groups?briefRepresentation=false", nil, &groups); err != nil {
+		return nil, err
+	}
+
+	byPath := map[string]map[string]interface{}{}
+	var walk func(groups []map[string]interface{})
+	walk = func(groups []map[string]interface{}) {
+		for _, group := range groups {
+			byPath[fmt.Sprint(group["path"])] = group
+			walk(toObjects(group["subGroups"]))
+		}
+	}
+	walk(groups)
+	return byPath, nil
+}
+
+func keycloakGroupID(token *keycloakToken, path string) (string, error) {
+	groups, err := keycloakGroupsByPath(token)
+	if err != nil {
+		return "", err
+	}
+	group, ok := groups[path]
+	if !ok {
+		return "", fmt.Errorf("group not found: %s", path)
+	}
+	return fmt.Sprint(group["id"]), nil
+}
+
+func keycloakClientUUID(token *keycloakToken, clientID string) (string, error) {
+	var clients []KeycloakClient
+	if err := keycloakAdminRequest(token, "GET", "/clients?clientId="+url.QueryEscape(clientID), nil, &clients); err != nil {
+		return "", err
+	}
+	for _, client := range clients {
+		if client.ClientID == clientID {
+			return client.ID, nil
+		}
+	}
+	return "", fmt.Errorf("client not found: %s", clientID)
+}
+
+// diffFields describes the fields of desired whose value differs in current,
+// ignoring internal IDs and the skipped fields.
+func diffFields(desired, current map[string]interface{}, skip ...string) []string {
+	skipped := map[string]bool{"id": true, "containerId": true}
+	for _, field := range skip {
+		skipped[field] = true
+	}
+
+	var names []string
+	for name := range desired {
+		if !skipped[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []string
+	for _, name := range names {
+		want := compactJSON(desired[name])
+		have := compactJSON(current[name])
+		if want != have {
+			changes = append(changes, fmt.Sprintf("    %s: %s -> %s", name, have, want))
+		}
+	}
+	return changes
+}
+
+// mergeFields returns a copy of current with the fields of desired laid over
+// it, leaving out the skipped fields of desired.
+func mergeFields(current, desired map[string]interface{}, skip ...string) map[string]interface{} {
+	skipped := map[string]bool{}
+	for _, field := range skip {
+		skipped[field] = true
+	}
+
+	merged := map[string]interface{}{}
+	for name, value := range current {
+		merged[name] = value
+	}
+	for name, value := range desired {
+		if !skipped[name] {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+func compactJSON(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(content)
+}
+
+// missingItems returns the items of want that are not in have.
+func missingItems(want, have []string) []string {
+	present := map[string]bool{}
+	for _, item := range have {
+		present[item] = true
+	}
+	var missing []string
+	for _, item := range want {
+		if !present[item] {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionsUsername string
+	sessionsOffline  bool
+	sessionsJSON     bool
+	logoutUsername   string
+	logoutSession    string
+	logoutAll        bool
+)
+
+// KeycloakSession is a user session as returned by the Keycloak admin API.
+type KeycloakSession struct {
+	ID         string            `json:"id"`
+	Username   string            `json:"username"`
+	UserID     string            `json:"userId"`
+	IPAddress  string            `json:"ipAddress"`
+	Start      int64             `json:"start"`
+	LastAccess int64             `json:"lastAccess"`
+	Clients    map[string]string `json:"clients"`
+	Offline    bool              `json:"offline"`
+}
+
+// keycloakSessionsCmd represents the keycloak sessions command
+var keycloakSessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List a user's active Keycloak sessions",
+	Long: `
+	Lists the active sessions of a Keycloak user with the IP address, start time, last
+	access time and the clients used. With --offline the offline sessions (refresh tokens
+	requested with offline access) of every client are listed as well.
+
+	Examples of the keycloak sessions command:
+		$ ./cliapp keycloak sessions --username=greg --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak sessions --username=greg --offline --format=json --client-id=cliapp-admin --client-secret-file=admin_client_secret.txt
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := getKeycloakAdminToken()
+		if err != nil {
+			log.Fatalf("Error getting Keycloak token: %v", err)
+		}
+
+		userID, err := getKeycloakUserID(token, sessionsUsername)
+		if err != nil {
+			log.Fatalf("Error fetching Keycloak user: %v", err)
+		}
+
+		var sessions []KeycloakSession
+		if err := keycloakAdminRequest(token, "GET", "/users/"+userID+"/sessions", nil, &sessions); err != nil {
+			log.Fatalf("Error fetching sessions: %v", err)
+		}
+
+		if sessionsOffline {
+			var clients []KeycloakClient
+			if err := keycloakAdminRequest(token, "GET", "/clients", nil, &clients); err != nil {
+				log.Fatalf("Error fetching clients: %v", err)
+			}
+			for _, client := range clients {
+				var offline []KeycloakSession
+				if err := keycloakAdminRequest(token, "GET", "/users/"+userID+"/offline-sessions/"+client.ID, nil, &offline); err != nil {
+					log.Fatalf("Error fetching offline sessions: %v", err)
+				}
+				for _, session := range offline {
+					session.Offline = true
+					sessions = append(sessions, session)
+				}
+			}
+		}
+
+		if sessionsJSON {
+			outputFormat = "json"
+		}
+		printFormatted(sessions, func() {
+			if len(sessions) == 0 {
+				fmt.Printf("No sessions for user '%s'.\n", sessionsUsername)
+				return
+			}
+			for _, session := range sessions {
+				kind := "online"
+				if session.Offline {
+					kind = "offline"
+				}
+				fmt.Printf("Session: %s (%s)\n", session.ID, kind)
+				fmt.Println("IP Address: " + session.IPAddress)
+				fmt.Println("Started: " + formatMillis(session.Start))
+				fmt.Println("Last Access: " + formatMillis(session.LastAccess))
+				for _, client := range session.Clients {
+					fmt.Println("Client: " + client)
+				}
+				fmt.Println()
+			}
+		})
+	},
+}
+
+// keycloakLogoutCmd represents the keycloak logout command
+var keycloakLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Force a logout of Keycloak sessions",
+	Long: `
+	Ends Keycloak sessions: all sessions of one user, a single session by ID, or every
+	session of the realm with --all. Vault tokens already issued stay valid until they
+	expire or are revoked.
+
+	Examples of the keycloak logout command:
+		$ ./cliapp keycloak logout --username=greg --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak logout --session=3f2a9c1e-... --adminUsername=admin --adminPassword=password
+
+		$ ./cliapp keycloak logout --all --adminUsername=admin --adminPassword=password
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		selected := 0
+		for _, set := range []bool{logoutUsername != "", logoutSession != "", logoutAll} {
+			if set {
+				selected++
+			}
+		}
+		if selected != 1 {
+			fmt.Println("Error: Provide exactly one of --username, --session or --all")
+			os.Exit(1)
+		}
+
+		token, err := getKeycloakAdminToken()
+		if err != nil {
+			log.Fatalf("Error getting Keycloak token: %v", err)
+		}
+
+		switch {
+		case logoutAll:
+			if err := keycloakAdminRequest(token, "POST", "/logout-all", nil, nil); err != nil {
+				log.Fatalf("Error logging out realm: %v", err)
+			}
+			fmt.Println("All sessions of realm '" + token.realm + "' logged out.")
+		case logoutSession != "":
+			if err := keycloakAdminRequest(token, "DELETE", "/sessions/"+logoutSession, nil, nil); err != nil {
+				log.Fatalf("Error deleting session: %v", err)
+			}
+			fmt.Println("Session '" + logoutSession + "' logged out.")
+		default:
+			userID, err := getKeycloakUserID(token, logoutUsername)
+			if err != nil {
+				log.Fatalf("Error fetching Keycloak user: %v", err)
+			}
+			if err := keycloakAdminRequest(token, "POST", "/users/"+userID+"/logout", nil, nil); err != nil {
+				log.Fatalf("Error logging out user: %v", err)
+			}
+			fmt.Println("All sessions of user '" + logoutUsername + "' logged out.")
+		}
+	},
+}
+
+func init() {
+	keycloakCmd.AddCommand(keycloakSessionsCmd)
+	keycloakCmd.AddCommand(keycloakLogoutCmd)
+
+	keycloakSessionsCmd.Flags().StringVarP(&sessionsUsername, "username", "s", "", "Username of the Keycloak user")
+	if err := keycloakSessionsCmd.MarkFlagRequired("username"); err != nil {
+		fmt.Println(err)
+	}
+	keycloakSessionsCmd.Flags().BoolVarP(&sessionsOffline, "offline", "o", false, "Include offline sessions")
+	keycloakSessionsCmd.Flags().BoolVarP(&sessionsJSON, "json", "j", false, "Print the sessions as JSON")
+	if err := keycloakSessionsCmd.Flags().MarkDeprecated("json", "use --format=json"); err != nil {
+		fmt.Println(err)
+	}
+	addKeycloakAdminFlags(keycloakSessionsCmd, "u", "p")
+
+	keycloakLogoutCmd.Flags().StringVarP(&logoutUsername, "username", "s", "", "Username of the Keycloak user")
+	keycloakLogoutCmd.Flags().StringVar(&logoutSession, "session", "", "ID of a single session")
+	keycloakLogoutCmd.Flags().BoolVar(&logoutAll, "all", false, "Log out every session of the realm")
+	addKeycloakAdminFlags(keycloakLogoutCmd, "u", "p")
+}
+
+// formatMillis formats a Keycloak timestamp in milliseconds.
+func formatMillis(millis int64) string {
+	return time.UnixMilli(millis).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	u18 string
+	p18 string
+)
+
+// kvCmd represents the kv command
+var kvCmd = &cobra.Command{
+	Use:   "kv",
+	Short: "Manage KV secrets engines",
+	Long: `
+	Manages the KV secrets engines themselves. The secret commands such as get, write,
+	update, delete and ls detect whether a mount is KV version 1 or 2 and work with both,
+	while versions, undelete and destroy need version 2. The upgrade command migrates a
+	version 1 mount to version 2.
+
+	Examples of the kv commands(Keycloak Authentication):
+		$ ./cliapp kv upgrade legacy
+
+	To use Userpass Authentication:
+		$ ./cliapp kv upgrade legacy --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp kv upgrade legacy --user=username --pass=password --instance
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(kvCmd)
+
+	addLoginFlags(kvCmd, &u18, &p18)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseKVArgs splits the arguments of write and update into the mount/path
+// argument and the key=value or key:=json pairs. A pair is recognised by an
+// "=" before any "/", so a path is never taken for a pair. The keys whose
+// values were read from files are returned with their file markers.
+func parseKVArgs(args []string) ([]string, map[string]interface{}, map[string]fileMarker, error) {
+	var target []string
+	pairs := map[string]interface{}{}
+	files := map[string]fileMarker{}
+	for _, arg := range args {
+		equals := strings.Index(arg, "=")
+		if equals < 0 || strings.Contains(arg[:equals], "/") {
+			target = append(target, arg)
+			continue
+		}
+		key, value, marker, err := parsePair(arg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if _, ok := pairs[key]; ok {
+			return nil, nil, nil, fmt.Errorf("key '%s' is given more than once", key)
+		}
+		pairs[key] = value
+		if marker != nil {
+			files[key] = *marker
+		}
+	}
+	if len(target) > 1 {
+		return nil, nil, nil, fmt.Errorf("only one mount/path argument can be given, got '%s'", strings.Join(target, "', '"))
+	}
+	return target, pairs, files, nil
+}
+
+// parsePair parses key=value as a string value and key:=value as a JSON
+// value, such as port:=8080, debug:=true or tags:=["a","b"]. A value of
+// @file is read from the file and a value of - from stdin. Binary files are
+// stored base64 encoded, and the marker returned records how to restore a
+// file value.
+func parsePair(pair string) (string, interface{}, *fileMarker, error) {
+	equals := strings.Index(pair, "=")
+	key, value := pair[:equals], pair[equals+1:]
+	typed := strings.HasSuffix(key, ":")
+	key = strings.TrimSuffix(key, ":")
+	if key == "" {
+		return "", nil, nil, fmt.Errorf("missing key in '%s'", pair)
+	}
+
+	content, file, err := readValue(key, value)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if typed {
+		parsed, err := decodeJSON(content)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("the value of '%s' is not valid JSON: %v", key, err)
+		}
+		return key, parsed, nil, nil
+	}
+
+	if len(content) == 0 {
+		return "", nil, nil, fmt.Errorf("key '%s' has an empty value, use %s:='\"\"' to store an empty string", key, key)
+	}
+	if file == nil {
+		return key, string(content), nil, nil
+	}
+	stored, marker := encodeFileValue(content, file.Mode().Perm())
+	return key, stored, &marker, nil
+}
+
+// stdinRead is set once stdin has been read for a value, since it can only
+// be read once.
+var stdinRead bool
+
+// readValue returns the value of a pair, reading it from a file for @file
+// and from stdin for -. A file is read as it is and returned with its
+// permissions, while the final newline of stdin is removed.
+func readValue(key, value string) ([]byte, os.FileInfo, error) {
+	switch {
+	case value == "-":
+		content, err := readStdin()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read the value of '%s' from stdin: %v", key, err)
+		}
+		return []byte(strings.TrimSuffix(strings.TrimSuffix(string(content), "\n"), "\r")), nil, nil
+	case strings.HasPrefix(value, "@"):
+		file := value[1:]
+		if file == "" {
+			return nil, nil, fmt.Errorf("missing file name after '@' for key '%s'", key)
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read the value of '%s': %v", key, err)
+		}
+		if info.IsDir() {
+			return nil, nil, fmt.Errorf("unable to read the value of '%s': '%s' is a directory", key, file)
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read the value of '%s': %v", key, err)
+		}
+		return content, info, nil
+	default:
+		return []byte(value), nil, nil
+	}
+}
+
+// readStdin reads all of stdin, failing if it was already read.
+func readStdin() ([]byte, error) {
+	if stdinRead {
+		return nil, fmt.Errorf("stdin can only be used once")
+	}
+	stdinRead = true
+	return ioutil.ReadAll(os.Stdin)
+}
+
+// readDataFile reads the data of a secret from a JSON or YAML document, or
+// from stdin when file is "-". YAML is used for .yaml and .yml files, and
+// for stdin when it is not JSON.
+func readDataFile(file string) (map[string]interface{}, error) {
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = readStdin()
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read '%s': %v", file, err)
+	}
+
+	if file != "-" && !isYAMLFile(file) {
+		data, err := decodeJSONObject(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON in '%s': %v", file, err)
+		}
+		return data, nil
+	}
+	if data, err := decodeJSONObject(content); err == nil {
+		return data, nil
+	}
+	var data map[string]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("invalid YAML in '%s': %v", file, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("'%s' has no keys", file)
+	}
+	return data, nil
+}
+
+// decodeJSON decodes a JSON value, keeping numbers as they are written so
+// that large integers do not lose precision.
+func decodeJSON(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// decodeJSONObject decodes a JSON object, the data of a secret.
+func decodeJSONObject(content []byte) (map[string]interface{}, error) {
+	value, err := decodeJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the JSON document must be an object of keys and values")
+	}
+	return object, nil
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to the data of a secret,
+// the same way Vault patches KV version 2 secrets: objects are merged key by
+// key, a null removes a key and any other value replaces it.
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range target {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		patchObject, isObject := value.(map[string]interface{})
+		targetObject, targetIsObject := merged[key].(map[string]interface{})
+		if isObject && targetIsObject {
+			merged[key] = mergePatch(targetObject, patchObject)
+		} else if isObject {
+			merged[key] = mergePatch(map[string]interface{}{}, patchObject)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// readMergePatch reads a JSON merge patch document from a file, or from
+// stdin when file is "-".
+func readMergePatch(file string) (map[string]interface{}, error) {
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = readStdin()
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read merge patch: %v", err)
+	}
+	patch, err := decodeJSONObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %v", err)
+	}
+	return patch, nil
+}
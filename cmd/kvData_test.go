@@ -0,0 +1,205 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePair(t *testing.T) {
+	tests := []struct {
+		pair    string
+		key     string
+		value   interface{}
+		wantErr string
+	}{
+		{pair: "user=admin", key: "user", value: "admin"},
+		{pair: "url=http://host/?a=b", key: "url", value: "http://host/?a=b"},
+		{pair: "port=8080", key: "port", value: "8080"},
+		{pair: "port:=8080", key: "port", value: json.Number("8080")},
+		{pair: "ratio:=0.25", key: "ratio", value: json.Number("0.25")},
+		{pair: "debug:=true", key: "debug", value: true},
+		{pair: "unset:=null", key: "unset", value: nil},
+		{pair: `empty:=""`, key: "empty", value: ""},
+		{pair: `tags:=["a","b"]`, key: "tags", value: []interface{}{"a", "b"}},
+		{pair: `db:={"port": 5432, "tls": {"on": false}}`, key: "db", value: map[string]interface{}{
+			"port": json.Number("5432"),
+			"tls":  map[string]interface{}{"on": false},
+		}},
+		{pair: "=value", wantErr: "missing key in '=value'"},
+		{pair: ":=1", wantErr: "missing key in ':=1'"},
+		{pair: "user=", wantErr: "key 'user' has an empty value"},
+		{pair: "port:=80 80", wantErr: "the value of 'port' is not valid JSON"},
+		{pair: "name:=admin", wantErr: "the value of 'name' is not valid JSON"},
+		{pair: "key=@", wantErr: "missing file name after '@' for key 'key'"},
+	}
+	for _, test := range tests {
+		t.Run(test.pair, func(t *testing.T) {
+			key, value, marker, err := parsePair(test.pair)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key != test.key || !reflect.DeepEqual(value, test.value) || marker != nil {
+				t.Errorf("parsePair = %q, %#v, %v, want %q, %#v, nil", key, value, marker, test.key, test.value)
+			}
+		})
+	}
+}
+
+func TestParsePairFromFile(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "cert.pem")
+	if err := ioutil.WriteFile(text, []byte("-----BEGIN-----\nabc\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	document := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(document, []byte(`{"retries": 3}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, value, marker, err := parsePair("cert=@" + text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantValue, wantMarker := encodeFileValue([]byte("-----BEGIN-----\nabc\n"), 0640)
+	if key != "cert" || value != wantValue || marker == nil || *marker != wantMarker {
+		t.Errorf("parsePair = %q, %#v, %v, want %q, %#v, %v", key, value, marker, "cert", wantValue, wantMarker)
+	}
+
+	key, value, marker, err = parsePair("config:=@" + document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := map[string]interface{}{"retries": json.Number("3")}
+	if key != "config" || !reflect.DeepEqual(value, wantJSON) || marker != nil {
+		t.Errorf("parsePair = %q, %#v, %v, want %q, %#v, nil", key, value, marker, "config", wantJSON)
+	}
+}
+
+func TestParseKVArgs(t *testing.T) {
+	target, pairs, files, err := parseKVArgs([]string{"secret/app/db", "user=admin", "port:=5432"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(target, []string{"secret/app/db"}) {
+		t.Errorf("target = %q, want [secret/app/db]", target)
+	}
+	wantPairs := map[string]interface{}{"user": "admin", "port": json.Number("5432")}
+	if !reflect.DeepEqual(pairs, wantPairs) {
+		t.Errorf("pairs = %#v, want %#v", pairs, wantPairs)
+	}
+	if len(files) != 0 {
+		t.Errorf("files = %v, want none", files)
+	}
+
+	target, _, _, err = parseKVArgs([]string{"secret/a=b/c", "key=value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(target, []string{"secret/a=b/c"}) {
+		t.Errorf("target = %q, want [secret/a=b/c]", target)
+	}
+
+	for _, args := range [][]string{
+		{"secret/app", "user=a", "user:=\"b\""},
+		{"secret/app", "secret/other", "user=a"},
+	} {
+		if _, _, _, err := parseKVArgs(args); err == nil {
+			t.Errorf("parseKVArgs(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		content string
+		want    interface{}
+		wantErr string
+	}{
+		{content: `12345678901234567890`, want: json.Number("12345678901234567890")},
+		{content: " {\"a\": [1, \"x\"]}\n", want: map[string]interface{}{"a": []interface{}{json.Number("1"), "x"}}},
+		{content: `"text"`, want: "text"},
+		{content: `null`, want: nil},
+		{content: `{"a": 1} {"b": 2}`, wantErr: "unexpected data after the JSON value"},
+		{content: `[1] x`, wantErr: "unexpected data after the JSON value"},
+		{content: `{"a": }`, wantErr: "invalid character"},
+		{content: ``, wantErr: "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.content, func(t *testing.T) {
+			got, err := decodeJSON([]byte(test.content))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("decodeJSON = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := decodeJSONObject([]byte(`["a"]`)); err == nil {
+		t.Error("decodeJSONObject succeeded on an array, want an error")
+	}
+}
+
+// The cases follow the examples of RFC 7386, appendix A.
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{name: "replace", target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add", target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null deletes", target: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "null deletes one of several", target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "null of a missing key", target: `{"a":"b"}`, patch: `{"c":null}`, want: `{"a":"b"}`},
+		{name: "array replaces", target: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "arrays are not merged", target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "nested merge", target: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"b":"x","d":null,"f":"g"}}`, want: `{"a":{"b":"x","f":"g"}}`},
+		{name: "object replaces a scalar", target: `{"a":"b"}`, patch: `{"a":{"c":"d","e":null}}`, want: `{"a":{"c":"d"}}`},
+		{name: "deep nulls", target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{name: "empty patch", target: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := decodeTestObject(t, test.target)
+			before := decodeTestObject(t, test.target)
+			got := mergePatch(target, decodeTestObject(t, test.patch))
+			if want := decodeTestObject(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("mergePatch = %#v, want %#v", got, want)
+			}
+			if !reflect.DeepEqual(target, before) {
+				t.Errorf("mergePatch changed the target to %#v", target)
+			}
+		})
+	}
+}
+
+func decodeTestObject(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+	object, err := decodeJSONObject([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
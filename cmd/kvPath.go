@@ -0,0 +1,181 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bufio"
+	"cliapp/auth"
+	"cliapp/util"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// kvMount describes the KV secrets engine that a path belongs to.
+type kvMount struct {
+	Path    string
+	Type    string
+	Version int
+}
+
+// lookupKVMount finds the KV mount that fullPath belongs to with the
+// sys/internal/ui/mounts endpoint, which any token with access to the path
+// may read. It returns the mount and the rest of the path inside it.
+func lookupKVMount(fullPath string) (*kvMount, string, error) {
+	fullPath = strings.Trim(fullPath, "/")
+	secret, err := auth.Client.Logical().Read("sys/internal/ui/mounts/" + fullPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to find the mount of '%s': %w", fullPath, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, "", fmt.Errorf("no mount found for '%s'", fullPath)
+	}
+
+	mountPath, _ := secret.Data["path"].(string)
+	mountType, _ := secret.Data["type"].(string)
+	if mountType != "kv" && mountType != "generic" {
+		return nil, "", fmt.Errorf("'%s' is a %s mount, not a KV mount", mountPath, mountType)
+	}
+
+	mount := &kvMount{Path: strings.TrimSuffix(mountPath, "/"), Type: mountType, Version: 1}
+	if options, ok := secret.Data["options"].(map[string]interface{}); ok && options["version"] == "2" {
+		mount.Version = 2
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(fullPath, mount.Path), "/")
+	return mount, rest, nil
+}
+
+// resolveKVArgs sets the mount and path of a KV command either from a single
+// positional "mount/path" argument or from the --mount and --path flags, and
+// returns the mount with its detected KV version. The path may only be empty
+// when pathRequired is false.
+func resolveKVArgs(args []string, mount, secretPath *string, pathRequired bool) *kvMount {
+	if len(args) == 1 {
+		if *mount != "" || *secretPath != "" {
+			fmt.Println("Error: Give either a mount/path argument or the --mount and --path flags, not both")
+			os.Exit(1)
+		}
+		kv, rest, err := lookupKVMount(args[0])
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		*mount = kv.Path
+		*secretPath = rest
+		if pathRequired {
+			util.ValidatePath(*secretPath)
+		}
+		return kv
+	}
+
+	*mount = strings.Trim(*mount, "/")
+	*secretPath = strings.Trim(*secretPath, "/")
+	util.ValidatePath(*mount)
+	if pathRequired {
+		util.ValidatePath(*secretPath)
+	}
+	if strings.HasPrefix(*secretPath, *mount+"/") {
+		fmt.Fprintf(os.Stderr, "Warning: The path '%s' starts with the mount name, so it refers to '%s/%s'. Use the argument '%s' to refer to '%s' in mount '%s'.\n",
+			*secretPath, *mount, *secretPath, *secretPath, strings.TrimPrefix(*secretPath, *mount+"/"), *mount)
+	}
+
+	kv, _, err := lookupKVMount(*mount + "/" + *secretPath)
+	if isPermissionDenied(err) {
+		// The token may not look up the mount, keep the behaviour of the
+		// flags before mounts were detected and assume KV version 2.
+		fmt.Fprintf(os.Stderr, "Warning: Unable to look up the mount '%s' (permission denied), assuming it is a KV version 2 mount.\n", *mount)
+		return &kvMount{Path: *mount, Type: "kv", Version: 2}
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if kv.Path != *mount {
+		fmt.Printf("Error: '%s' is not a mount, '%s/%s' is inside the mount '%s'. Use the argument '%s/%s' instead.\n",
+			*mount, *mount, *secretPath, kv.Path, *mount, *secretPath)
+		os.Exit(1)
+	}
+	return kv
+}
+
+// isPermissionDenied reports whether Vault refused a request with a 403.
+func isPermissionDenied(err error) bool {
+	var responseError *vault.ResponseError
+	return errors.As(err, &responseError) && responseError.StatusCode == 403
+}
+
+// resolveKVArg resolves a single "mount/path" argument of a command that
+// takes several paths.
+func resolveKVArg(arg string) (*kvMount, string) {
+	kv, rest, err := lookupKVMount(arg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	util.ValidatePath(rest)
+	return kv, rest
+}
+
+// requireKVv2 stops an operation that only exists for KV version 2 mounts.
+func requireKVv2(kv *kvMount, operation string) {
+	if kv.Version != 2 {
+		fmt.Printf("Error: %s is only available on KV version 2 mounts, '%s' is KV version %d. Use 'cliapp kv upgrade %s' to upgrade it.\n",
+			operation, kv.Path, kv.Version, kv.Path)
+		os.Exit(1)
+	}
+}
+
+// readSecret reads the current version of a secret from a KV mount of
+// either version. Secrets of version 1 mounts have no version metadata.
+func readSecret(kv *kvMount, secretPath string) (*vault.KVSecret, error) {
+	if kv.Version == 2 {
+		return auth.Client.KVv2(kv.Path).Get(context.Background(), secretPath)
+	}
+	return auth.Client.KVv1(kv.Path).Get(context.Background(), secretPath)
+}
+
+// writeSecret replaces the data of a secret on a KV mount of either version.
+// The options, such as check-and-set, only apply to version 2 mounts.
+func writeSecret(kv *kvMount, secretPath string, data map[string]interface{}, opts ...vault.KVOption) error {
+	if kv.Version == 2 {
+		_, err := auth.Client.KVv2(kv.Path).Put(context.Background(), secretPath, data, opts...)
+		return err
+	}
+	return auth.Client.KVv1(kv.Path).Put(context.Background(), secretPath, data)
+}
+
+// patchSecret applies a JSON merge patch to an existing secret. Version 1
+// mounts have no patch operation, so the secret is read, merged and written
+// back.
+func patchSecret(kv *kvMount, secretPath string, data map[string]interface{}, opts ...vault.KVOption) error {
+	if kv.Version == 2 {
+		_, err := auth.Client.KVv2(kv.Path).Patch(context.Background(), secretPath, data, opts...)
+		return err
+	}
+	secret, err := readSecret(kv, secretPath)
+	if err != nil {
+		return err
+	}
+	return writeSecret(kv, secretPath, mergePatch(secret.Data, data))
+}
+
+// deleteSecret deletes a secret, which for version 2 mounts is a soft delete
+// of the current version.
+func deleteSecret(kv *kvMount, secretPath string) error {
+	if kv.Version == 2 {
+		return auth.Client.KVv2(kv.Path).Delete(context.Background(), secretPath)
+	}
+	return auth.Client.KVv1(kv.Path).Delete(context.Background(), secretPath)
+}
+
+// confirm asks a yes/no question on the terminal. Anything but y or yes is
+// a no.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var kvUpgradeTimeout time.Duration
+
+// kvUpgradeCmd represents the kv upgrade command
+var kvUpgradeCmd = &cobra.Command{
+	Use:   "upgrade <mount>",
+	Short: "Upgrade a KV version 1 mount to version 2",
+	Long: `
+	Upgrades a KV version 1 mount to version 2 by tuning its version option. Vault migrates
+	the existing secrets in the background and the mount cannot be used until it is done,
+	so the command waits for the upgrade up to the timeout (0 to not wait). Policies that
+	grant access to "<mount>/*" must be changed to the "<mount>/data/*" and
+	"<mount>/metadata/*" paths afterwards.
+
+	Example of the kv upgrade command(Keycloak Authentication):
+		$ ./cliapp kv upgrade legacy
+
+		$ ./cliapp kv upgrade legacy --timeout=5m
+
+	To use Userpass Authentication:
+		$ ./cliapp kv upgrade legacy --user=username --pass=password
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u18, p18)
+
+		kv, rest, err := lookupKVMount(args[0])
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if rest != "" {
+			fmt.Printf("Error: '%s' is not a mount, the mount is '%s'\n", args[0], kv.Path)
+			os.Exit(1)
+		}
+		if kv.Version == 2 {
+			fmt.Printf("Mount '%s' is already KV version 2.\n", kv.Path)
+			return
+		}
+
+		err = auth.Client.Sys().TuneMount(kv.Path, vault.MountConfigInput{
+			Options: map[string]string{"version": "2"},
+		})
+		if err != nil {
+			log.Fatalf("unable to upgrade mount '%s': %v", kv.Path, err)
+		}
+		fmt.Printf("Upgrade of mount '%s' to KV version 2 started.\n", kv.Path)
+
+		if kvUpgradeTimeout > 0 {
+			if err := waitForUpgrade(kv.Path, kvUpgradeTimeout); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Mount '%s' upgraded to KV version 2.\n", kv.Path)
+		}
+		fmt.Printf("Update the policies for '%s/*' to use '%s/data/*' and '%s/metadata/*'.\n", kv.Path, kv.Path, kv.Path)
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvUpgradeCmd)
+
+	kvUpgradeCmd.Flags().DurationVarP(&kvUpgradeTimeout, "timeout", "t", time.Minute, "How long to wait for the upgrade to finish, 0 to not wait")
+}
+
+// waitForUpgrade polls the metadata of the mount, which cannot be listed
+// while Vault is still migrating the secrets.
+func waitForUpgrade(mount string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := auth.Client.Logical().List(mount + "/metadata/")
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("mount '%s' is still upgrading after %s: %v", mount, timeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
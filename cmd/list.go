@@ -8,6 +8,7 @@ import (
 	"cliapp/util"
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/spf13/cobra"
 )
@@ -18,6 +19,14 @@ var (
 	instance bool
 )
 
+// mountOutput is the machine-readable form of a mount printed by list.
+type mountOutput struct {
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Version     string `json:"version,omitempty"`
+}
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
@@ -34,6 +43,9 @@ var listCmd = &cobra.Command{
 
 	To use a different instance:
 		$ ./cliapp list --user=username --pass=password --instance
+
+	To print the mounts as JSON:
+		$ ./cliapp list --format=json
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
@@ -54,10 +66,23 @@ var listCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println("Available mount paths:")
-		for path := range mounts {
-			fmt.Println(path)
+		var output []mountOutput
+		for path, mount := range mounts {
+			output = append(output, mountOutput{
+				Path:        path,
+				Type:        mount.Type,
+				Description: mount.Description,
+				Version:     mount.Options["version"],
+			})
 		}
+		sort.Slice(output, func(i, j int) bool { return output[i].Path < output[j].Path })
+
+		printFormatted(output, func() {
+			fmt.Println("Available mount paths:")
+			for _, mount := range output {
+				fmt.Println(mount.Path)
+			}
+		})
 	},
 }
 
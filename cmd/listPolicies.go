@@ -0,0 +1,66 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	u17 string
+	p17 string
+)
+
+// listPoliciesCmd represents the listPolicies command
+var listPoliciesCmd = &cobra.Command{
+	Use:   "listPolicies",
+	Short: "List the ACL policies",
+	Long: `
+	List the ACL policies of the Vault server.
+
+	Example of the listPolicies command(Keycloak Authentication):
+		$ ./cliapp listPolicies
+
+	To use Userpass Authentication:
+		$ ./cliapp listPolicies --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp listPolicies --user=username --pass=password --instance
+
+	To print the policies as JSON:
+		$ ./cliapp listPolicies --format=json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u17, p17)
+
+		policies, err := auth.Client.Sys().ListPolicies()
+		if err != nil {
+			log.Fatalf("unable to list policies: %v", err)
+		}
+		sort.Strings(policies)
+
+		printFormatted(policies, func() {
+			fmt.Println("Policies:")
+			for _, policy := range policies {
+				fmt.Println(policy)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listPoliciesCmd)
+
+	// userpass
+	listPoliciesCmd.Flags().StringVarP(&u17, "user", "u", "", "Userpass username")
+	listPoliciesCmd.Flags().StringVarP(&p17, "pass", "a", "", "Userpass password")
+	listPoliciesCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	listPoliciesCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	u16 string
+	p16 string
+)
+
+// listUsersCmd represents the listUsers command
+var listUsersCmd = &cobra.Command{
+	Use:   "listUsers",
+	Short: "List the userpass users",
+	Long: `
+	List the users of the userpass auth method with their policies.
+
+	Example of the listUsers command(Keycloak Authentication):
+		$ ./cliapp listUsers
+
+	To use Userpass Authentication:
+		$ ./cliapp listUsers --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp listUsers --user=username --pass=password --instance
+
+	To print the users as JSON:
+		$ ./cliapp listUsers --format=json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u16, p16)
+
+		secret, err := auth.Client.Logical().List("auth/userpass/users")
+		if err != nil {
+			log.Fatalf("unable to list users: %v", err)
+		}
+
+		var output []userOutput
+		if secret != nil {
+			users := toStrings(secret.Data["keys"])
+			sort.Strings(users)
+			for _, user := range users {
+				details, err := auth.Client.Logical().Read("auth/userpass/users/" + user)
+				if err != nil {
+					log.Fatalf("unable to read user '%s': %v", user, err)
+				}
+				entry := userOutput{Username: user}
+				if details != nil {
+					entry.Policies = toStrings(details.Data["token_policies"])
+				}
+				output = append(output, entry)
+			}
+		}
+
+		printFormatted(output, func() {
+			fmt.Println("Users:")
+			for _, user := range output {
+				fmt.Printf("%s %v\n", user.Username, user.Policies)
+			}
+		})
+	},
+}
+
+// userOutput is the machine-readable form of a user printed by listUsers.
+type userOutput struct {
+	Username string   `json:"username"`
+	Policies []string `json:"policies"`
+}
+
+func init() {
+	rootCmd.AddCommand(listUsersCmd)
+
+	// userpass
+	listUsersCmd.Flags().StringVarP(&u16, "user", "u", "", "Userpass username")
+	listUsersCmd.Flags().StringVarP(&p16, "pass", "a", "", "Userpass password")
+	listUsersCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	listUsersCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// login authenticates a command the same way as the single commands do:
+// with userpass when --user and --pass are given, otherwise with Keycloak.
+// It is used by the command groups, which share these flags between their
+// subcommands as persistent flags.
+func login(cmd *cobra.Command, user, pass string) {
+	if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
+		address := util.UpdateAddress(instance)
+		if err := auth.AuthenticateWithUserPass(user, pass, address); err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else {
+		if cmd.Flag("instance").Changed {
+			fmt.Println("Error: You must provide a username and password to use a different instance.")
+			os.Exit(1)
+		}
+		address := util.UpdateAddress(false)
+		auth.KeycloakAuth(address)
+	}
+}
+
+// addLoginFlags registers the userpass and instance flags on a command group.
+func addLoginFlags(cmd *cobra.Command, user, pass *string) {
+	cmd.PersistentFlags().StringVarP(user, "user", "u", "", "Userpass username")
+	cmd.PersistentFlags().StringVarP(pass, "pass", "a", "", "Userpass password")
+	cmd.MarkFlagsRequiredTogether("user", "pass")
+
+	cmd.PersistentFlags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
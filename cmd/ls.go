@@ -0,0 +1,279 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lsMount     string
+	lsPath      string
+	lsRecursive bool
+	lsDepth     int
+	lsGlob      string
+	lsOutput    string
+	lsWorkers   int
+	u15         string
+	p15         string
+)
+
+// kvEntry is a secret or folder found under a KV mount. Paths are relative
+// to the mount and folders end with a slash.
+type kvEntry struct {
+	Path string `json:"path"`
+	Dir  bool   `json:"folder"`
+}
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls [mount/path]",
+	Short: "List the secrets under a path of a KV mount",
+	Long: `
+	Lists the secrets and folders under a path of a KV mount. With -R the folders are walked
+	recursively, listing several folders at once, down to the given depth (0 means no limit).
+	The glob filters the secrets shown, matching the secret name, or the whole path when the
+	glob contains a slash. The output is a tree or a flat list of paths, or the entries in
+	the format given with --format.
+
+	Examples of the ls command(Keycloak Authentication):
+		$ ./cliapp ls kv
+
+		$ ./cliapp ls kv/team -R
+
+		$ ./cliapp ls kv -R --depth=2 --glob="*db*" --output=flat
+
+		$ ./cliapp ls kv -R --format=json
+
+	The mount is found from the mount table. The mount and the folder inside it can also be given with flags:
+		$ ./cliapp ls --mount=kv --path=team -R
+
+	To use with Userpass Authentication:
+		$ ./cliapp ls kv -R --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp ls kv -R --user=username --pass=password --instance
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if lsOutput != "tree" && lsOutput != "flat" && lsOutput != "json" {
+			fmt.Println("Error: The output must be tree or flat (json is deprecated, use --format=json)")
+			os.Exit(1)
+		}
+		if lsOutput == "json" {
+			fmt.Fprintln(os.Stderr, "Warning: --output=json is deprecated, use --format=json")
+			outputFormat = "json"
+		}
+		if lsDepth < 0 || lsWorkers < 1 {
+			fmt.Println("Error: The depth cannot be negative and at least one worker is required")
+			os.Exit(1)
+		}
+		if _, err := pathpkg.Match(lsGlob, ""); err != nil {
+			fmt.Println("Error: Invalid glob:", err)
+			os.Exit(1)
+		}
+
+		login(cmd, u15, p15)
+		kv := resolveKVArgs(args, &lsMount, &lsPath, false)
+
+		depth := 1
+		if lsRecursive {
+			depth = lsDepth
+		}
+		entries, err := walkSecrets(kv, lsPath, depth, lsWorkers)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		entries = filterEntries(entries, lsGlob)
+
+		if outputFormat != "table" {
+			printFormatted(entries, nil)
+			return
+		}
+		switch lsOutput {
+		case "flat":
+			for _, entry := range entries {
+				fmt.Println(entry.Path)
+			}
+		default:
+			printTree(lsMount, lsPath, entries)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+
+	//mount
+	lsCmd.Flags().StringVarP(&lsMount, "mount", "m", "", "The mount path to list secrets from")
+
+	// path
+	lsCmd.Flags().StringVarP(&lsPath, "path", "p", "", "folder to list, the root of the mount by default")
+
+	lsCmd.Flags().BoolVarP(&lsRecursive, "recursive", "R", false, "List folders recursively")
+	lsCmd.Flags().IntVarP(&lsDepth, "depth", "d", 0, "Maximum depth when recursive, 0 for no limit")
+	lsCmd.Flags().StringVarP(&lsGlob, "glob", "g", "*", "Only show secrets matching the glob")
+	lsCmd.Flags().StringVarP(&lsOutput, "output", "o", "tree", "Output style: tree or flat, use --format for json or yaml")
+	lsCmd.Flags().IntVarP(&lsWorkers, "workers", "w", 8, "Number of folders listed at once")
+
+	// userpass
+	lsCmd.Flags().StringVarP(&u15, "user", "u", "", "Userpass username")
+	lsCmd.Flags().StringVarP(&p15, "pass", "a", "", "Userpass password")
+	lsCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	lsCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// listSecrets returns the keys directly under a folder of a KV mount.
+func listSecrets(kv *kvMount, folder string) ([]string, error) {
+	listPath := kv.Path + "/" + folder
+	if kv.Version == 2 {
+		listPath = kv.Path + "/metadata/" + folder
+	}
+	secret, err := auth.Client.Logical().List(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list '%s': %v", folder, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+	return toStrings(secret.Data["keys"]), nil
+}
+
+// walkSecrets lists the folder and, down to maxDepth levels (0 for no
+// limit), its subfolders. At most workers folders are listed at once. The
+// entries are returned sorted by path.
+func walkSecrets(kv *kvMount, root string, maxDepth, workers int) ([]kvEntry, error) {
+	root = strings.Trim(root, "/")
+	if root != "" {
+		root += "/"
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		entries  []kvEntry
+		firstErr error
+		slots    = make(chan struct{}, workers)
+	)
+
+	var visit func(folder string, depth int)
+	visit = func(folder string, depth int) {
+		defer wg.Done()
+
+		slots <- struct{}{}
+		keys, err := listSecrets(kv, folder)
+		<-slots
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		for _, key := range keys {
+			entry := kvEntry{Path: folder + key, Dir: strings.HasSuffix(key, "/")}
+			entries = append(entries, entry)
+			if entry.Dir && (maxDepth == 0 || depth < maxDepth) {
+				wg.Add(1)
+				go visit(entry.Path, depth+1)
+			}
+		}
+	}
+
+	wg.Add(1)
+	go visit(root, 1)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// filterEntries keeps the secrets matching the glob and the folders leading
+// to them. Folders are kept as they are when the glob matches everything.
+func filterEntries(entries []kvEntry, glob string) []kvEntry {
+	if glob == "" || glob == "*" {
+		return entries
+	}
+
+	keep := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Dir || !matchGlob(glob, entry.Path) {
+			continue
+		}
+		keep[entry.Path] = true
+		for dir := pathpkg.Dir(entry.Path); dir != "." && dir != "/"; dir = pathpkg.Dir(dir) {
+			keep[dir+"/"] = true
+		}
+	}
+
+	var filtered []kvEntry
+	for _, entry := range entries {
+		if keep[entry.Path] {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// matchGlob matches the secret's name, or its whole path when the glob
+// contains a slash.
+func matchGlob(glob, secretPath string) bool {
+	name := secretPath
+	if !strings.Contains(glob, "/") {
+		name = pathpkg.Base(secretPath)
+	}
+	matched, _ := pathpkg.Match(glob, name)
+	return matched
+}
+
+func printTree(mount, root string, entries []kvEntry) {
+	root = strings.Trim(root, "/")
+	if root == "" {
+		fmt.Println(strings.TrimSuffix(mount, "/") + "/")
+	} else {
+		fmt.Println(strings.TrimSuffix(mount, "/") + "/" + root + "/")
+		root += "/"
+	}
+
+	children := map[string][]kvEntry{}
+	for _, entry := range entries {
+		parent := pathpkg.Dir(strings.TrimSuffix(entry.Path, "/")) + "/"
+		if parent == "./" {
+			parent = ""
+		}
+		children[parent] = append(children[parent], entry)
+	}
+
+	var print func(folder, indent string)
+	print = func(folder, indent string) {
+		list := children[folder]
+		for i, entry := range list {
+			branch, next := "├── ", "│   "
+			if i == len(list)-1 {
+				branch, next = "└── ", "    "
+			}
+			name := strings.TrimPrefix(entry.Path, folder)
+			fmt.Println(indent + branch + name)
+			if entry.Dir {
+				print(entry.Path, indent+next)
+			}
+		}
+	}
+	print(root, "")
+}
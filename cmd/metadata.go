@@ -0,0 +1,75 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	metaMount              string
+	metaPath               string
+	metaMaxVersions        int
+	metaCASRequired        bool
+	metaDeleteVersionAfter time.Duration
+	metaCustom             []string
+	u19                    string
+	p19                    string
+)
+
+// metadataCmd represents the metadata command
+var metadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Manage the metadata of a KV version 2 secret",
+	Long: `
+	Manages the metadata of a secret on a KV version 2 mount: the maximum number of versions
+	kept, whether writes must use check-and-set, how long a version lives before it is
+	deleted, and custom key/value annotations such as the owner, a description or the
+	rotation interval. The get command also lists every version of the secret with its
+	created, deleted and destroyed state.
+
+	Examples of the metadata commands(Keycloak Authentication):
+		$ ./cliapp metadata get secret/my-secret
+
+		$ ./cliapp metadata put secret/my-secret --max-versions=5 --custom=owner=team-a
+
+		$ ./cliapp metadata patch secret/my-secret --custom=rotation=90d --remove-custom=description
+
+		$ ./cliapp metadata delete secret/my-secret
+
+	To use Userpass Authentication:
+		$ ./cliapp metadata get secret/my-secret --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp metadata get secret/my-secret --user=username --pass=password --instance
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(metadataCmd)
+
+	metadataCmd.PersistentFlags().StringVarP(&metaMount, "mount", "m", "", "The mount path of the secret")
+	metadataCmd.PersistentFlags().StringVarP(&metaPath, "path", "p", "", "path to the secret")
+
+	addLoginFlags(metadataCmd, &u19, &p19)
+}
+
+// addMetadataFlags registers the settings that metadata put and patch change.
+func addMetadataFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&metaMaxVersions, "max-versions", 0, "Number of versions kept, 0 for the mount's default")
+	cmd.Flags().BoolVar(&metaCASRequired, "cas-required", false, "Require check-and-set on every write")
+	cmd.Flags().DurationVar(&metaDeleteVersionAfter, "delete-version-after", 0, "Delete versions after this long, such as 720h, 0 to keep them")
+	cmd.Flags().StringArrayVar(&metaCustom, "custom", nil, "Custom metadata as key=value, can be repeated")
+}
+
+func validateMetadataFlags() {
+	if metaMaxVersions < 0 || metaDeleteVersionAfter < 0 {
+		fmt.Println("Error: The max versions and delete version after cannot be negative")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var metaDeleteYes bool
+
+// metadataDeleteCmd represents the metadata delete command
+var metadataDeleteCmd = &cobra.Command{
+	Use:   "delete [mount/path]",
+	Short: "Permanently delete a secret with all its versions and metadata",
+	Long: `
+	Permanently deletes the metadata of a secret together with every version of its data.
+	This cannot be undone, so the command asks for confirmation unless --yes is given.
+
+	Example of the metadata delete command(Keycloak Authentication):
+		$ ./cliapp metadata delete secret/my-secret
+
+		$ ./cliapp metadata delete secret/my-secret --yes
+
+	To use Userpass Authentication:
+		$ ./cliapp metadata delete secret/my-secret --user=username --pass=password
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u19, p19)
+		kv := resolveKVArgs(args, &metaMount, &metaPath, true)
+		requireKVv2(kv, "Secret metadata")
+
+		if !metaDeleteYes && !confirm(fmt.Sprintf("Delete all versions and metadata of '%s/%s'?", kv.Path, metaPath)) {
+			fmt.Println("Nothing deleted.")
+			return
+		}
+
+		err := auth.Client.KVv2(kv.Path).DeleteMetadata(context.Background(), metaPath)
+		if err != nil {
+			log.Fatalf("unable to delete metadata: %v", err)
+		}
+
+		fmt.Println("Secret and metadata deleted.")
+	},
+}
+
+func init() {
+	metadataCmd.AddCommand(metadataDeleteCmd)
+
+	metadataDeleteCmd.Flags().BoolVarP(&metaDeleteYes, "yes", "y", false, "Do not ask for confirmation")
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+// metadataGetCmd represents the metadata get command
+var metadataGetCmd = &cobra.Command{
+	Use:   "get [mount/path]",
+	Short: "Show the metadata and versions of a secret",
+	Long: `
+	Shows the settings and custom metadata of a secret followed by all of its versions,
+	each with the time it was created and deleted and whether it is active, deleted or
+	destroyed.
+
+	Example of the metadata get command(Keycloak Authentication):
+		$ ./cliapp metadata get secret/my-secret
+
+		$ ./cliapp metadata get secret/my-secret --format=json
+
+	To use Userpass Authentication:
+		$ ./cliapp metadata get --mount=secret --path=my-secret --user=username --pass=password
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		login(cmd, u19, p19)
+		kv := resolveKVArgs(args, &metaMount, &metaPath, true)
+		requireKVv2(kv, "Secret metadata")
+
+		metadata, err := auth.Client.KVv2(kv.Path).GetMetadata(context.Background(), metaPath)
+		if err != nil {
+			log.Fatalf("unable to read metadata: %v", err)
+		}
+		output := newMetadataOutput(kv.Path, metaPath, metadata)
+
+		printFormatted(output, func() {
+			fmt.Println("Path: " + output.Mount + "/" + output.Path)
+			fmt.Printf("Current Version: %d\n", output.CurrentVersion)
+			fmt.Printf("Oldest Version: %d\n", output.OldestVersion)
+			fmt.Printf("Max Versions: %d\n", output.MaxVersions)
+			fmt.Printf("CAS Required: %t\n", output.CASRequired)
+			fmt.Println("Delete Version After: " + output.DeleteVersionAfter)
+			fmt.Println("Time Created: " + output.CreatedTime)
+			fmt.Println("Time Updated: " + output.UpdatedTime)
+
+			if len(output.CustomMetadata) > 0 {
+				fmt.Println("Custom Metadata:")
+				var keys []string
+				for key := range output.CustomMetadata {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					fmt.Printf("  %s: %v\n", key, output.CustomMetadata[key])
+				}
+			}
+
+			fmt.Println("Versions:")
+			for _, version := range output.Versions {
+				fmt.Printf("  %d\t%s\tcreated %s", version.Version, version.State, version.CreatedTime)
+				if version.DeletionTime != "" {
+					fmt.Printf("\tdeleted %s", version.DeletionTime)
+				}
+				fmt.Println()
+			}
+		})
+	},
+}
+
+func init() {
+	metadataCmd.AddCommand(metadataGetCmd)
+}
+
+// metadataOutput is the machine-readable form of the metadata of a secret.
+type metadataOutput struct {
+	Mount              string                 `json:"mount"`
+	Path               string                 `json:"path"`
+	CurrentVersion     int                    `json:"current_version"`
+	OldestVersion      int                    `json:"oldest_version"`
+	MaxVersions        int                    `json:"max_versions"`
+	CASRequired        bool                   `json:"cas_required"`
+	DeleteVersionAfter string                 `json:"delete_version_after"`
+	CreatedTime        string                 `json:"created_time"`
+	UpdatedTime        string                 `json:"updated_time"`
+	CustomMetadata     map[string]interface{} `json:"custom_metadata,omitempty"`
+	Versions           []versionOutput        `json:"versions"`
+}
+
+type versionOutput struct {
+	Version      int    `json:"version"`
+	State        string `json:"state"`
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time,omitempty"`
+	Destroyed    bool   `json:"destroyed"`
+}
+
+func newMetadataOutput(mount, secretPath string, metadata *vault.KVMetadata) metadataOutput {
+	output := metadataOutput{
+		Mount:              mount,
+		Path:               secretPath,
+		CurrentVersion:     metadata.CurrentVersion,
+		OldestVersion:      metadata.OldestVersion,
+		MaxVersions:        metadata.MaxVersions,
+		CASRequired:        metadata.CASRequired,
+		DeleteVersionAfter: metadata.DeleteVersionAfter.String(),
+		CreatedTime:        formatTime(metadata.CreatedTime),
+		UpdatedTime:        formatTime(metadata.UpdatedTime),
+		CustomMetadata:     metadata.CustomMetadata,
+	}
+	for _, version := range versionList(metadata) {
+		output.Versions = append(output.Versions, versionOutput{
+			Version:      version.Version,
+			State:        versionState(version),
+			CreatedTime:  formatTime(version.CreatedTime),
+			DeletionTime: formatTime(version.DeletionTime),
+			Destroyed:    version.Destroyed,
+		})
+	}
+	return output
+}
+
+// versionList returns the versions of the metadata sorted by number.
+func versionList(metadata *vault.KVMetadata) []vault.KVVersionMetadata {
+	var versions []vault.KVVersionMetadata
+	for _, version := range metadata.Versions {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions
+}
+
+// versionState describes a version as active, deleted or destroyed.
+func versionState(version vault.KVVersionMetadata) string {
+	switch {
+	case version.Destroyed:
+		return "destroyed"
+	case !version.DeletionTime.IsZero():
+		return "deleted"
+	default:
+		return "active"
+	}
+}
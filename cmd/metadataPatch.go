@@ -0,0 +1,97 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metaRemoveCustom []string
+	metaClearCustom  bool
+)
+
+// metadataPatchCmd represents the metadata patch command
+var metadataPatchCmd = &cobra.Command{
+	Use:   "patch [mount/path]",
+	Short: "Change some of the metadata settings of a secret",
+	Long: `
+	Changes only the metadata settings that are given and keeps the others. Custom metadata
+	keys are added or replaced one by one, and can be removed with --remove-custom or all
+	at once with --clear-custom.
+
+	Example of the metadata patch command(Keycloak Authentication):
+		$ ./cliapp metadata patch secret/my-secret --max-versions=20
+
+		$ ./cliapp metadata patch secret/my-secret --custom=rotation=90d --remove-custom=description
+
+		$ ./cliapp metadata patch secret/my-secret --clear-custom
+
+	To use Userpass Authentication:
+		$ ./cliapp metadata patch secret/my-secret --cas-required=false --user=username --pass=password
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		validateMetadataFlags()
+		custom, err := parseMetadata(metaCustom)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if metaClearCustom && (len(metaCustom) > 0 || len(metaRemoveCustom) > 0) {
+			fmt.Println("Error: --clear-custom cannot be used with --custom or --remove-custom")
+			os.Exit(1)
+		}
+
+		var patch vault.KVMetadataPatchInput
+		if cmd.Flags().Changed("max-versions") {
+			patch.MaxVersions = &metaMaxVersions
+		}
+		if cmd.Flags().Changed("cas-required") {
+			patch.CASRequired = &metaCASRequired
+		}
+		if cmd.Flags().Changed("delete-version-after") {
+			patch.DeleteVersionAfter = &metaDeleteVersionAfter
+		}
+		if metaClearCustom {
+			patch.CustomMetadata = map[string]interface{}{}
+		} else if len(custom) > 0 || len(metaRemoveCustom) > 0 {
+			// keys set to null are removed by the merge patch
+			for _, key := range metaRemoveCustom {
+				custom[key] = nil
+			}
+			patch.CustomMetadata = custom
+		}
+		if patch.MaxVersions == nil && patch.CASRequired == nil && patch.DeleteVersionAfter == nil && patch.CustomMetadata == nil {
+			fmt.Println("Error: Nothing to change, give at least one metadata setting")
+			os.Exit(1)
+		}
+
+		login(cmd, u19, p19)
+		kv := resolveKVArgs(args, &metaMount, &metaPath, true)
+		requireKVv2(kv, "Secret metadata")
+
+		err = auth.Client.KVv2(kv.Path).PatchMetadata(context.Background(), metaPath, patch)
+		if err != nil {
+			log.Fatalf("unable to patch metadata: %v", err)
+		}
+
+		fmt.Println("Metadata updated successfully.")
+	},
+}
+
+func init() {
+	metadataCmd.AddCommand(metadataPatchCmd)
+
+	addMetadataFlags(metadataPatchCmd)
+	metadataPatchCmd.Flags().StringArrayVar(&metaRemoveCustom, "remove-custom", nil, "Custom metadata key to remove, can be repeated")
+	metadataPatchCmd.Flags().BoolVar(&metaClearCustom, "clear-custom", false, "Remove all custom metadata")
+}
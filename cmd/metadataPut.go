@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+// metadataPutCmd represents the metadata put command
+var metadataPutCmd = &cobra.Command{
+	Use:   "put [mount/path]",
+	Short: "Replace the metadata settings of a secret",
+	Long: `
+	Replaces all the metadata settings of a secret. Settings that are not given are reset,
+	so the custom metadata given replaces the existing annotations. Use metadata patch to
+	change only some of them.
+
+	Example of the metadata put command(Keycloak Authentication):
+		$ ./cliapp metadata put secret/my-secret --max-versions=10 --cas-required
+
+		$ ./cliapp metadata put secret/my-secret --delete-version-after=720h --custom=owner=team-a --custom=description="Database login"
+
+	To use Userpass Authentication:
+		$ ./cliapp metadata put secret/my-secret --max-versions=10 --user=username --pass=password
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		validateMetadataFlags()
+		custom, err := parseMetadata(metaCustom)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		login(cmd, u19, p19)
+		kv := resolveKVArgs(args, &metaMount, &metaPath, true)
+		requireKVv2(kv, "Secret metadata")
+
+		err = auth.Client.KVv2(kv.Path).PutMetadata(context.Background(), metaPath, vault.KVMetadataPutInput{
+			CASRequired:        metaCASRequired,
+			CustomMetadata:     custom,
+			DeleteVersionAfter: metaDeleteVersionAfter,
+			MaxVersions:        metaMaxVersions,
+		})
+		if err != nil {
+			log.Fatalf("unable to write metadata: %v", err)
+		}
+
+		fmt.Println("Metadata written successfully.")
+	},
+}
+
+func init() {
+	metadataCmd.AddCommand(metadataPutCmd)
+
+	addMetadataFlags(metadataPutCmd)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	moveLatestOnly bool
+	u25            string
+	p25            string
+)
+
+// mvCmd represents the mv command
+var mvCmd = &cobra.Command{
+	Use:   "mv <mount/path> <mount/path>",
+	Short: "Move secrets between paths, mounts and instances",
+	Long: `
+	Moves a secret, or with -R every secret under a folder, to another path. Each secret is
+	copied like cp does and then deleted from the source with all of its versions and
+	metadata. Between KV version 2 mounts every version that is not deleted or destroyed
+	and the metadata are copied, so the history is kept; --latest-only moves only the
+	current version. A secret with several versions is not moved to a KV version 1 mount,
+	which keeps only the last, unless --latest-only is given. The destination can be on
+	another mount, and with --to-instance on the other Vault instance. Existing destination
+	secrets are only overwritten with --force, and --dry-run lists what would be moved.
+
+	Examples of the mv command(Keycloak Authentication):
+		$ ./cliapp mv secret/my-secret secret/archive/
+
+		$ ./cliapp mv secret/team kv/team -R --latest-only
+
+		$ ./cliapp mv secret/team kv/team -R --dry-run
+
+	To use Userpass Authentication:
+		$ ./cliapp mv secret/my-secret kv/my-secret --user=username --pass=password
+
+	To move to the other instance:
+		$ ./cliapp mv secret/team secret/team -R --to-instance --user=username --pass=password
+	`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		copySecrets(cmd, args, u25, p25, true)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+
+	addCopyFlags(mvCmd)
+	mvCmd.Flags().BoolVar(&moveLatestOnly, "latest-only", false, "Move only the current version, dropping the history of the source")
+	mvCmd.MarkFlagsMutuallyExclusive("latest-only", "versions")
+	mvCmd.MarkFlagsMutuallyExclusive("latest-only", "metadata")
+
+	// userpass
+	mvCmd.Flags().StringVarP(&u25, "user", "u", "", "Userpass username")
+	mvCmd.Flags().StringVarP(&p25, "pass", "a", "", "Userpass password")
+	mvCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	mvCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
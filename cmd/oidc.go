@@ -0,0 +1,57 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	oidcMount string
+	u12       string
+	p12       string
+)
+
+// oidcCmd represents the oidc command
+var oidcCmd = &cobra.Command{
+	Use:   "oidc",
+	Short: "Configure the JWT/OIDC auth method that trusts Keycloak",
+	Long: `
+	Configures the Vault JWT or OIDC auth method used to log in with Keycloak. The setup
+	command enables the auth method and points it at the Keycloak realm's discovery URL,
+	the role commands create, update and inspect the roles that map Keycloak tokens to
+	Vault policies. The auth method is mounted at "jwt" unless --mount is given.
+
+	Examples of the oidc commands(Keycloak Authentication):
+		$ ./cliapp oidc setup
+
+		$ ./cliapp oidc role write user-policy --bound-audiences=vault-client --policies=user-policy
+
+		$ ./cliapp oidc show
+
+	To use Userpass Authentication:
+		$ ./cliapp oidc show --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp oidc show --user=username --pass=password --instance
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(oidcCmd)
+
+	oidcCmd.PersistentFlags().StringVarP(&oidcMount, "mount", "m", "jwt", "Path the auth method is mounted at")
+
+	addLoginFlags(oidcCmd, &u12, &p12)
+}
+
+func validateOidcMount() {
+	if oidcMount == "" {
+		fmt.Println("Error: A mount path is required")
+		os.Exit(1)
+	}
+}
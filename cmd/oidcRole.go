@@ -0,0 +1,275 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	roleName           string
+	roleBoundAudiences string
+	roleUserClaim      string
+	roleGroupsClaim    string
+	rolePolicies       string
+	roleTTL            string
+	roleMaxTTL         string
+	roleRedirectURIs   string
+)
+
+// oidcRoleCmd represents the oidc role command
+var oidcRoleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Create, update and inspect JWT/OIDC roles",
+	Long: `
+	Roles decide which Keycloak tokens may log in and which policies they receive. A role
+	binds the audiences (the Keycloak client ID), the claim used as the user name and the
+	claim holding the Keycloak groups.
+
+	Examples of the oidc role commands(Keycloak Authentication):
+		$ ./cliapp oidc role write --name=user-policy --bound-audiences=vault-client --policies=user-policy --ttl=1h --max-ttl=24h
+
+		$ ./cliapp oidc role read --name=user-policy
+
+		$ ./cliapp oidc role list
+
+		$ ./cliapp oidc role delete --name=old-role
+	`,
+}
+
+// oidcRoleWriteCmd represents the oidc role write command
+var oidcRoleWriteCmd = &cobra.Command{
+	Use:   "write",
+	Short: "Create or update a JWT/OIDC role",
+	Long: `
+	Creates a role, or updates the given fields of an existing role. A new role uses the
+	"sub" claim as the user name unless --user-claim is given. Roles on an oidc mount need
+	the allowed redirect URIs.
+
+	Examples of the oidc role write command(Keycloak Authentication):
+		$ ./cliapp oidc role write --name=user-policy --bound-audiences=vault-client --policies=user-policy --ttl=1h --max-ttl=24h
+
+		$ ./cliapp oidc role write --name=user-policy --groups-claim=groups
+
+		$ ./cliapp oidc role write --mount=oidc --name=default --policies=default --allowed-redirect-uris=http://localhost:8250/oidc/callback
+
+	To use Userpass Authentication:
+		$ ./cliapp oidc role write --name=user-policy --policies=user-policy --user=username --pass=password
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateOidcMount()
+		login(cmd, u12, p12)
+
+		rolePath := "auth/" + oidcMount + "/role/" + roleName
+		existing, err := auth.Client.Logical().Read(rolePath)
+		if err != nil {
+			log.Fatalf("unable to read role: %v", err)
+		}
+
+		data := map[string]interface{}{}
+		if existing == nil {
+			mountType, err := authMountType(oidcMount)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			data["role_type"] = mountType
+			data["user_claim"] = roleUserClaim
+		}
+
+		stringFlags := map[string]string{
+			"user-claim":   "user_claim",
+			"groups-claim": "groups_claim",
+			"ttl":          "token_ttl",
+			"max-ttl":      "token_max_ttl",
+		}
+		for flag, field := range stringFlags {
+			if cmd.Flag(flag).Changed {
+				data[field] = cmd.Flag(flag).Value.String()
+			}
+		}
+
+		listFlags := map[string]string{
+			"bound-audiences":       "bound_audiences",
+			"policies":              "token_policies",
+			"allowed-redirect-uris": "allowed_redirect_uris",
+		}
+		for flag, field := range listFlags {
+			if cmd.Flag(flag).Changed {
+				data[field] = splitList(cmd.Flag(flag).Value.String())
+			}
+		}
+
+		if len(data) == 0 {
+			fmt.Println("Error: Nothing to update, provide at least one role field")
+			os.Exit(1)
+		}
+
+		if _, err := auth.Client.Logical().Write(rolePath, data); err != nil {
+			log.Fatalf("unable to write role: %v", err)
+		}
+
+		if existing == nil {
+			fmt.Printf("Role '%s' created on: %s\n", roleName, oidcMount)
+		} else {
+			fmt.Printf("Role '%s' updated on: %s\n", roleName, oidcMount)
+		}
+	},
+}
+
+// oidcRoleReadCmd represents the oidc role read command
+var oidcRoleReadCmd = &cobra.Command{
+	Use:   "read",
+	Short: "Show a JWT/OIDC role",
+	Long: `
+	Shows the fields of a role.
+
+	Example of the oidc role read command(Keycloak Authentication):
+		$ ./cliapp oidc role read --name=user-policy
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateOidcMount()
+		login(cmd, u12, p12)
+
+		role, err := auth.Client.Logical().Read("auth/" + oidcMount + "/role/" + roleName)
+		if err != nil {
+			log.Fatalf("unable to read role: %v", err)
+		}
+		if role == nil {
+			fmt.Printf("Role '%s' not found on: %s\n", roleName, oidcMount)
+			os.Exit(1)
+		}
+
+		fmt.Println("Role: " + roleName)
+		printFields(role.Data)
+	},
+}
+
+// oidcRoleListCmd represents the oidc role list command
+var oidcRoleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the JWT/OIDC roles",
+	Long: `
+	Lists the roles on the auth method.
+
+	Example of the oidc role list command(Keycloak Authentication):
+		$ ./cliapp oidc role list
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateOidcMount()
+		login(cmd, u12, p12)
+
+		roles, err := listOidcRoles()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		fmt.Println("Roles on " + oidcMount + ":")
+		for _, role := range roles {
+			fmt.Println(role)
+		}
+	},
+}
+
+// oidcRoleDeleteCmd represents the oidc role delete command
+var oidcRoleDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a JWT/OIDC role",
+	Long: `
+	Deletes a role. Logins naming the role will fail afterwards.
+
+	Example of the oidc role delete command(Keycloak Authentication):
+		$ ./cliapp oidc role delete --name=old-role
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateOidcMount()
+		login(cmd, u12, p12)
+
+		if _, err := auth.Client.Logical().Delete("auth/" + oidcMount + "/role/" + roleName); err != nil {
+			log.Fatalf("unable to delete role: %v", err)
+		}
+
+		fmt.Printf("Role '%s' deleted from: %s\n", roleName, oidcMount)
+	},
+}
+
+func init() {
+	oidcCmd.AddCommand(oidcRoleCmd)
+	oidcRoleCmd.AddCommand(oidcRoleWriteCmd)
+	oidcRoleCmd.AddCommand(oidcRoleReadCmd)
+	oidcRoleCmd.AddCommand(oidcRoleListCmd)
+	oidcRoleCmd.AddCommand(oidcRoleDeleteCmd)
+
+	for _, c := range []*cobra.Command{oidcRoleWriteCmd, oidcRoleReadCmd, oidcRoleDeleteCmd} {
+		c.Flags().StringVarP(&roleName, "name", "n", "", "Name of the role")
+		if err := c.MarkFlagRequired("name"); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	oidcRoleWriteCmd.Flags().StringVarP(&roleBoundAudiences, "bound-audiences", "b", "", "Comma separated audiences the token must have")
+	oidcRoleWriteCmd.Flags().StringVarP(&roleUserClaim, "user-claim", "c", "sub", "Claim used as the user name")
+	oidcRoleWriteCmd.Flags().StringVarP(&roleGroupsClaim, "groups-claim", "g", "", "Claim holding the Keycloak groups")
+	oidcRoleWriteCmd.Flags().StringVarP(&rolePolicies, "policies", "p", "", "Comma separated policies given on login")
+	oidcRoleWriteCmd.Flags().StringVarP(&roleTTL, "ttl", "t", "", "Token TTL, e.g. 1h")
+	oidcRoleWriteCmd.Flags().StringVarP(&roleMaxTTL, "max-ttl", "x", "", "Token max TTL, e.g. 24h")
+	oidcRoleWriteCmd.Flags().StringVarP(&roleRedirectURIs, "allowed-redirect-uris", "r", "", "Comma separated redirect URIs (oidc roles)")
+}
+
+func authMountType(mount string) (string, error) {
+	enabled, err := auth.Client.Sys().ListAuth()
+	if err != nil {
+		return "", fmt.Errorf("unable to list auth methods: %v", err)
+	}
+	existing, ok := enabled[mount+"/"]
+	if !ok {
+		return "", fmt.Errorf("no auth method enabled at '%s', run oidc setup first", mount)
+	}
+	return existing.Type, nil
+}
+
+func listOidcRoles() ([]string, error) {
+	secret, err := auth.Client.Logical().List("auth/" + oidcMount + "/role")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %v", err)
+	}
+
+	var roles []string
+	if secret != nil && secret.Data["keys"] != nil {
+		for _, role := range secret.Data["keys"].([]interface{}) {
+			roles = append(roles, role.(string))
+		}
+	}
+	return roles, nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// printFields prints a Vault response's fields sorted by name.
+func printFields(data map[string]interface{}) {
+	var names []string
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s: %v\n", name, data[name])
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	oidcType             string
+	oidcDiscoveryURL     string
+	oidcClientID         string
+	oidcClientSecretFile string
+	oidcDefaultRole      string
+	oidcBoundIssuer      string
+)
+
+// oidcSetupCmd represents the oidc setup command
+var oidcSetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Enable the JWT/OIDC auth method and point it at Keycloak",
+	Long: `
+	Enables the jwt or oidc auth method at the mount path, if it is not already enabled, and
+	writes its config so that Vault discovers Keycloak's signing keys from the realm's
+	discovery URL. The oidc type also needs the Keycloak client ID and secret, which is read
+	from a file. Running setup again updates the config.
+
+	Examples of the oidc setup command(Keycloak Authentication):
+		$ ./cliapp oidc setup
+
+		$ ./cliapp oidc setup --discovery-url=http://localhost:8080/auth/realms/my_realm --default-role=user-policy
+
+		$ ./cliapp oidc setup --type=oidc --mount=oidc --client-secret-file=client_secret.txt --default-role=default
+
+	To use Userpass Authentication:
+		$ ./cliapp oidc setup --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp oidc setup --user=username --pass=password --instance
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateOidcMount()
+		if oidcType != "jwt" && oidcType != "oidc" {
+			fmt.Println("Error: The type must be jwt or oidc")
+			os.Exit(1)
+		}
+		if oidcDiscoveryURL == "" {
+			fmt.Println("Error: A discovery URL is required")
+			os.Exit(1)
+		}
+
+		login(cmd, u12, p12)
+
+		enabled, err := auth.Client.Sys().ListAuth()
+		if err != nil {
+			log.Fatalf("unable to list auth methods: %v", err)
+		}
+		if existing, ok := enabled[oidcMount+"/"]; ok {
+			if existing.Type != "jwt" && existing.Type != "oidc" {
+				fmt.Printf("Error: Auth method at '%s' is of type '%s'\n", oidcMount, existing.Type)
+				os.Exit(1)
+			}
+		} else {
+			if err := auth.Client.Sys().EnableAuth(oidcMount, oidcType, "Keycloak"); err != nil {
+				log.Fatalf("unable to enable auth method: %v", err)
+			}
+			fmt.Printf("Auth method '%s' enabled at: %s\n", oidcType, oidcMount)
+		}
+
+		config := map[string]interface{}{
+			"oidc_discovery_url": oidcDiscoveryURL,
+			"default_role":       oidcDefaultRole,
+		}
+		if oidcBoundIssuer != "" {
+			config["bound_issuer"] = oidcBoundIssuer
+		}
+		if oidcType == "oidc" {
+			secretBytes, err := ioutil.ReadFile(oidcClientSecretFile)
+			if err != nil {
+				log.Fatalf("unable to read client secret: %v", err)
+			}
+			config["oidc_client_id"] = oidcClientID
+			config["oidc_client_secret"] = strings.TrimSpace(string(secretBytes))
+		}
+
+		if _, err := auth.Client.Logical().Write("auth/"+oidcMount+"/config", config); err != nil {
+			log.Fatalf("unable to configure auth method: %v", err)
+		}
+
+		fmt.Println("Auth method at '" + oidcMount + "' now trusts: " + oidcDiscoveryURL)
+	},
+}
+
+func init() {
+	oidcCmd.AddCommand(oidcSetupCmd)
+
+	oidcSetupCmd.Flags().StringVarP(&oidcType, "type", "t", "jwt", "Auth method type, jwt or oidc")
+	oidcSetupCmd.Flags().StringVarP(&oidcDiscoveryURL, "discovery-url", "d", "http://localhost:8080/auth/realms/my_realm", "Keycloak realm discovery URL")
+	oidcSetupCmd.Flags().StringVarP(&oidcClientID, "client-id", "c", "vault-client", "Keycloak client ID (oidc type)")
+	oidcSetupCmd.Flags().StringVarP(&oidcClientSecretFile, "client-secret-file", "s", "client_secret.txt", "File containing the Keycloak client secret (oidc type)")
+	oidcSetupCmd.Flags().StringVarP(&oidcDefaultRole, "default-role", "r", "user-policy", "Role used when a login does not name one")
+	oidcSetupCmd.Flags().StringVarP(&oidcBoundIssuer, "bound-issuer", "b", "", "Issuer the tokens must be issued by")
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// oidcShowCmd represents the oidc show command
+var oidcShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the Keycloak trust configured in Vault",
+	Long: `
+	Shows the auth method's type and config, such as the discovery URL and default role,
+	followed by every role and its bound audiences, claims and policies.
+
+	Example of the oidc show command(Keycloak Authentication):
+		$ ./cliapp oidc show
+
+	To use Userpass Authentication:
+		$ ./cliapp oidc show --mount=oidc --user=username --pass=password
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateOidcMount()
+		login(cmd, u12, p12)
+
+		mountType, err := authMountType(oidcMount)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		config, err := auth.Client.Logical().Read("auth/" + oidcMount + "/config")
+		if err != nil {
+			log.Fatalf("unable to read config: %v", err)
+		}
+
+		fmt.Printf("Mount: %s (%s)\n", oidcMount, mountType)
+		if config == nil {
+			fmt.Println("Not configured, run oidc setup")
+			return
+		}
+		printFields(config.Data)
+
+		roles, err := listOidcRoles()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, name := range roles {
+			role, err := auth.Client.Logical().Read("auth/" + oidcMount + "/role/" + name)
+			if err != nil {
+				log.Fatalf("unable to read role: %v", err)
+			}
+			fmt.Println()
+			fmt.Println("Role: " + name)
+			if role != nil {
+				printFields(role.Data)
+			}
+		}
+	},
+}
+
+func init() {
+	oidcCmd.AddCommand(oidcShowCmd)
+}
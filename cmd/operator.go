@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	operatorToken     string
+	operatorTokenFile string
+)
+
+// operatorCmd represents the operator command
+var operatorCmd = &cobra.Command{
+	Use:   "operator",
+	Short: "Initialize, unseal, seal and bootstrap a Vault server",
+	Long: `
+	Operator commands manage the lifecycle of a Vault server. They replace the curl and jq
+	steps of init_unseal_vault.sh: a new server is initialized, unsealed with the threshold
+	of key shares and then bootstrapped with the policies and auth methods used by this app.
+	Use the --instance flag to target the second Vault instance.
+
+	Examples of the operator commands:
+		$ ./cliapp operator init --shares=5 --threshold=3 --output=vault-init.json
+
+		$ ./cliapp operator unseal --key-file=vault-init.json
+
+		$ ./cliapp operator seal-status --instance
+
+		$ ./cliapp operator bootstrap --token-file=vault-init.json
+
+		$ ./cliapp operator seal --token-file=vault-init.json
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(operatorCmd)
+
+	operatorCmd.PersistentFlags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// readInitFile reads the JSON written by "operator init --output". It returns
+// nil without an error when the file is not in that format.
+func readInitFile(file string) (*vault.InitResponse, []byte, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read file '%s': %v", file, err)
+	}
+
+	var initResponse vault.InitResponse
+	if err := json.Unmarshal(content, &initResponse); err != nil {
+		return nil, content, nil
+	}
+	return &initResponse, content, nil
+}
+
+// getOperatorToken resolves the token for the operator commands that need one,
+// from --token, --token-file (an init output or a plain token file) or VAULT_TOKEN.
+func getOperatorToken() string {
+	if operatorToken != "" {
+		return operatorToken
+	}
+
+	if operatorTokenFile != "" {
+		initResponse, content, err := readInitFile(operatorTokenFile)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if initResponse != nil && initResponse.RootToken != "" {
+			return initResponse.RootToken
+		}
+		return strings.TrimSpace(string(content))
+	}
+
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token
+	}
+
+	fmt.Println("Error: A token is required, use --token, --token-file or VAULT_TOKEN")
+	os.Exit(1)
+	return ""
+}
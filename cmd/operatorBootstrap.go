@@ -0,0 +1,103 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bootstrapPolicyDir string
+	bootstrapUsers     []string
+)
+
+var bootstrapPolicies = []string{"admin-policy.hcl", "user-policy.hcl"}
+var bootstrapAuthMethods = []string{"userpass", "jwt"}
+
+// operatorBootstrapCmd represents the operator bootstrap command
+var operatorBootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Write the app policies and enable its auth methods",
+	Long: `
+	Prepares a freshly unsealed Vault server for this app. It writes the admin-policy and
+	user-policy from the policies directory and enables the userpass and jwt auth methods,
+	skipping any that are already enabled. Userpass users can be created at the same time
+	in the form username:password:policy. The root token from init is normally used.
+
+	Examples of the operator bootstrap command:
+		$ ./cliapp operator bootstrap --token-file=vault-init.json
+
+		$ ./cliapp operator bootstrap --token-file=vault-init.json --create-user=admin:admin:admin-policy --create-user=user:pass:user-policy
+
+	To use a different instance:
+		$ ./cliapp operator bootstrap --token-file=vault-init2.json --instance
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		address := util.UpdateAddress(instance)
+		if err := auth.AuthenticateWithToken(getOperatorToken(), address); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		for _, file := range bootstrapPolicies {
+			if err := WritePolicy(file, filepath.Join(bootstrapPolicyDir, file)); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		enabled, err := auth.Client.Sys().ListAuth()
+		if err != nil {
+			log.Fatalf("unable to list auth methods: %v", err)
+		}
+		for _, method := range bootstrapAuthMethods {
+			if _, ok := enabled[method+"/"]; ok {
+				fmt.Printf("Auth method '%s' already enabled.\n", method)
+				continue
+			}
+			if err := auth.Client.Sys().EnableAuth(method, method, ""); err != nil {
+				log.Fatalf("unable to enable auth method '%s': %v", method, err)
+			}
+			fmt.Printf("Auth method '%s' enabled.\n", method)
+		}
+
+		for _, entry := range bootstrapUsers {
+			parts := strings.SplitN(entry, ":", 3)
+			if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+				fmt.Println("Error: Users must be given as username:password:policy")
+				os.Exit(1)
+			}
+			username := strings.ToLower(parts[0])
+			exists, err := UserExists(username)
+			if err != nil {
+				log.Fatalf("unable to list users: %v", err)
+			}
+			if exists {
+				fmt.Printf("User '%s' already exists.\n", username)
+				continue
+			}
+			if err := AddUserWithPolicy(username, parts[1], parts[2]); err != nil {
+				log.Fatalf("unable to add user: %v", err)
+			}
+		}
+
+		fmt.Println("Vault bootstrapped at: " + address)
+	},
+}
+
+func init() {
+	operatorCmd.AddCommand(operatorBootstrapCmd)
+
+	operatorBootstrapCmd.Flags().StringVarP(&operatorToken, "token", "t", "", "Vault token")
+	operatorBootstrapCmd.Flags().StringVarP(&operatorTokenFile, "token-file", "f", "", "File containing the token or the init output")
+	operatorBootstrapCmd.Flags().StringVarP(&bootstrapPolicyDir, "policy-dir", "d", "./policies", "Directory containing the policy files")
+	operatorBootstrapCmd.Flags().StringArrayVarP(&bootstrapUsers, "create-user", "c", nil, "Userpass user to create as username:password:policy (can be repeated)")
+}
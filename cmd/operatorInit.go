@@ -0,0 +1,154 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initShares     int
+	initThreshold  int
+	initPGPKeys    string
+	initRootPGPKey string
+	initOutput     string
+)
+
+// operatorInitCmd represents the operator init command
+var operatorInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Initialize a new Vault server",
+	Long: `
+	Initializes a new Vault server. The root key is split into the given number of key
+	shares, of which the threshold is needed to unseal Vault. The key shares can be
+	encrypted with PGP public keys, one file per share, given as a comma separated list.
+	The keys and root token are printed and can also be saved to a file with --output,
+	which the unseal, seal and bootstrap commands accept.
+
+	Examples of the operator init command:
+		$ ./cliapp operator init
+
+		$ ./cliapp operator init --shares=5 --threshold=3 --output=vault-init.json
+
+		$ ./cliapp operator init --shares=3 --threshold=2 --pgp-keys=alice.gpg,bob.gpg,carol.gpg
+
+	To use a different instance:
+		$ ./cliapp operator init --output=vault-init2.json --instance
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if initShares < 1 || initThreshold < 1 || initThreshold > initShares {
+			fmt.Println("Error: The threshold must be between 1 and the number of shares")
+			os.Exit(1)
+		}
+		if initShares > 1 && initThreshold == 1 {
+			fmt.Println("Error: The threshold must be greater than one when using multiple shares")
+			os.Exit(1)
+		}
+
+		address := util.UpdateAddress(instance)
+		client, err := auth.NewClient(address)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		initialized, err := client.Sys().InitStatus()
+		if err != nil {
+			log.Fatalf("unable to read init status: %v", err)
+		}
+		if initialized {
+			fmt.Println("Vault is already initialized at: " + address)
+			os.Exit(1)
+		}
+
+		request := &vault.InitRequest{
+			SecretShares:    initShares,
+			SecretThreshold: initThreshold,
+		}
+
+		if initPGPKeys != "" {
+			pgpKeys, err := readPGPKeys(strings.Split(initPGPKeys, ","))
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if len(pgpKeys) != initShares {
+				fmt.Println("Error: The number of PGP keys must match the number of shares")
+				os.Exit(1)
+			}
+			request.PGPKeys = pgpKeys
+		}
+		if initRootPGPKey != "" {
+			rootKey, err := readPGPKeys([]string{initRootPGPKey})
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			request.RootTokenPGPKey = rootKey[0]
+		}
+
+		response, err := client.Sys().Init(request)
+		if err != nil {
+			log.Fatalf("unable to initialize Vault: %v", err)
+		}
+
+		for i, key := range response.KeysB64 {
+			fmt.Printf("Unseal Key %d: %s\n", i+1, key)
+		}
+		fmt.Printf("\nInitial Root Token: %s\n\n", response.RootToken)
+		fmt.Printf("Vault initialized with %d key shares and a key threshold of %d.\n", initShares, initThreshold)
+
+		if initOutput != "" {
+			content, err := json.MarshalIndent(response, "", "  ")
+			if err != nil {
+				log.Fatalf("unable to encode init response: %v", err)
+			}
+			if err := ioutil.WriteFile(initOutput, content, 0600); err != nil {
+				log.Fatalf("Error writing init response to file: %v", err)
+			}
+			fmt.Printf("Keys and root token saved in file: %s\n", initOutput)
+		}
+	},
+}
+
+func init() {
+	operatorCmd.AddCommand(operatorInitCmd)
+
+	operatorInitCmd.Flags().IntVarP(&initShares, "shares", "n", 5, "Number of key shares to split the root key into")
+	operatorInitCmd.Flags().IntVarP(&initThreshold, "threshold", "t", 3, "Number of key shares required to unseal")
+	operatorInitCmd.Flags().StringVarP(&initPGPKeys, "pgp-keys", "g", "", "Comma separated PGP public key files to encrypt the key shares")
+	operatorInitCmd.Flags().StringVarP(&initRootPGPKey, "root-token-pgp-key", "r", "", "PGP public key file to encrypt the root token")
+	operatorInitCmd.Flags().StringVarP(&initOutput, "output", "o", "", "File to save the keys and root token to")
+}
+
+// readPGPKeys loads binary PGP public keys and base64 encodes them as Vault
+// expects. Entries starting with "keybase:" are passed through unchanged.
+func readPGPKeys(files []string) ([]string, error) {
+	var keys []string
+	for _, file := range files {
+		file = strings.TrimSpace(file)
+		if strings.HasPrefix(file, "keybase:") {
+			keys = append(keys, file)
+			continue
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read PGP key '%s': %v", file, err)
+		}
+		if strings.Contains(string(content), "BEGIN PGP") {
+			return nil, fmt.Errorf("PGP key '%s' is ASCII armored, export it in binary form (gpg --export)", file)
+		}
+		keys = append(keys, base64.StdEncoding.EncodeToString(content))
+	}
+	return keys, nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// operatorSealCmd represents the operator seal command
+var operatorSealCmd = &cobra.Command{
+	Use:   "seal",
+	Short: "Seal a Vault server",
+	Long: `
+	Seals the Vault server. Once sealed, Vault must be unsealed with the key shares again
+	before secrets can be read. Sealing requires a token with sudo on sys/seal, such as
+	the root token.
+
+	Examples of the operator seal command:
+		$ ./cliapp operator seal --token-file=vault-init.json
+
+		$ ./cliapp operator seal --token=hvs.XXXX
+
+	To use a different instance:
+		$ ./cliapp operator seal --token-file=vault-init2.json --instance
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		address := util.UpdateAddress(instance)
+		if err := auth.AuthenticateWithToken(getOperatorToken(), address); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		if err := auth.Client.Sys().Seal(); err != nil {
+			log.Fatalf("unable to seal Vault: %v", err)
+		}
+
+		fmt.Println("Vault sealed at: " + address)
+	},
+}
+
+func init() {
+	operatorCmd.AddCommand(operatorSealCmd)
+
+	operatorSealCmd.Flags().StringVarP(&operatorToken, "token", "t", "", "Vault token")
+	operatorSealCmd.Flags().StringVarP(&operatorTokenFile, "token-file", "f", "", "File containing the token or the init output")
+}
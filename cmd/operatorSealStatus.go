@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"fmt"
+	"log"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+// operatorSealStatusCmd represents the operator seal-status command
+var operatorSealStatusCmd = &cobra.Command{
+	Use:   "seal-status",
+	Short: "Show the seal status of a Vault server",
+	Long: `
+	Shows whether the Vault server is initialized and sealed, the key shares and threshold,
+	and the progress of an unseal in progress. No authentication is required.
+
+	Example of the operator seal-status command:
+		$ ./cliapp operator seal-status
+
+	To use a different instance:
+		$ ./cliapp operator seal-status --instance
+
+	To print the status as JSON:
+		$ ./cliapp operator seal-status --format=json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		address := util.UpdateAddress(instance)
+		client, err := auth.NewClient(address)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		status, err := client.Sys().SealStatus()
+		if err != nil {
+			log.Fatalf("unable to read seal status: %v", err)
+		}
+
+		output := struct {
+			Address string `json:"address"`
+			*vault.SealStatusResponse
+		}{address, status}
+
+		printFormatted(output, func() {
+			fmt.Println("Address: " + address)
+			fmt.Println("Seal Type: " + status.Type)
+			fmt.Printf("Initialized: %t\n", status.Initialized)
+			fmt.Printf("Sealed: %t\n", status.Sealed)
+			fmt.Printf("Total Shares: %d\n", status.N)
+			fmt.Printf("Threshold: %d\n", status.T)
+			if status.Sealed {
+				fmt.Printf("Unseal Progress: %d/%d\n", status.Progress, status.T)
+				fmt.Println("Unseal Nonce: " + status.Nonce)
+			}
+			fmt.Println("Version: " + status.Version)
+			fmt.Println("Storage Type: " + status.StorageType)
+			if status.ClusterName != "" {
+				fmt.Println("Cluster Name: " + status.ClusterName)
+				fmt.Println("Cluster ID: " + status.ClusterID)
+			}
+		})
+	},
+}
+
+func init() {
+	operatorCmd.AddCommand(operatorSealStatusCmd)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"cliapp/util"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	unsealKeyFiles []string
+	unsealReset    bool
+)
+
+// operatorUnsealCmd represents the operator unseal command
+var operatorUnsealCmd = &cobra.Command{
+	Use:   "unseal",
+	Short: "Unseal a Vault server with its key shares",
+	Long: `
+	Unseals a Vault server by providing key shares until the threshold is reached. The keys
+	are read from key files, either the output of "operator init --output" or a file with one
+	key per line, or else you are prompted for them. Input at the prompt is hidden.
+
+	Examples of the operator unseal command:
+		$ ./cliapp operator unseal
+
+		$ ./cliapp operator unseal --key-file=vault-init.json
+
+		$ ./cliapp operator unseal --key-file=key1.txt --key-file=key2.txt --key-file=key3.txt
+
+		$ ./cliapp operator unseal --reset
+
+	To use a different instance:
+		$ ./cliapp operator unseal --key-file=vault-init2.json --instance
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		address := util.UpdateAddress(instance)
+		client, err := auth.NewClient(address)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		if unsealReset {
+			if _, err := client.Sys().ResetUnsealProcess(); err != nil {
+				log.Fatalf("unable to reset unseal process: %v", err)
+			}
+			fmt.Println("Unseal process reset.")
+			return
+		}
+
+		status, err := client.Sys().SealStatus()
+		if err != nil {
+			log.Fatalf("unable to read seal status: %v", err)
+		}
+		if !status.Sealed {
+			fmt.Println("Vault is already unsealed.")
+			return
+		}
+
+		if len(unsealKeyFiles) > 0 {
+			var keys []string
+			for _, file := range unsealKeyFiles {
+				fileKeys, err := readUnsealKeys(file)
+				if err != nil {
+					log.Fatalf("%v", err)
+				}
+				keys = append(keys, fileKeys...)
+			}
+
+			for _, key := range keys {
+				status = submitUnsealKey(client, key)
+				if !status.Sealed {
+					break
+				}
+			}
+		} else {
+			for status.Sealed {
+				fmt.Printf("Unseal Key (%d/%d, will be hidden): ", status.Progress+1, status.T)
+				key, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if err != nil {
+					log.Fatalf("unable to read unseal key: %v", err)
+				}
+				if strings.TrimSpace(string(key)) == "" {
+					break
+				}
+				status = submitUnsealKey(client, strings.TrimSpace(string(key)))
+			}
+		}
+
+		if status.Sealed {
+			fmt.Printf("Vault is still sealed, unseal progress: %d/%d\n", status.Progress, status.T)
+			os.Exit(1)
+		}
+		fmt.Println("Vault server is now unsealed and ready to use.")
+	},
+}
+
+func init() {
+	operatorCmd.AddCommand(operatorUnsealCmd)
+
+	operatorUnsealCmd.Flags().StringArrayVarP(&unsealKeyFiles, "key-file", "k", nil, "File containing unseal keys (can be repeated)")
+	operatorUnsealCmd.Flags().BoolVarP(&unsealReset, "reset", "r", false, "Discard the unseal keys provided so far")
+}
+
+func submitUnsealKey(client *vault.Client, key string) *vault.SealStatusResponse {
+	status, err := client.Sys().Unseal(key)
+	if err != nil {
+		log.Fatalf("unable to unseal Vault: %v", err)
+	}
+	fmt.Printf("Unseal progress: %d/%d\n", status.Progress, status.T)
+	return status
+}
+
+// readUnsealKeys returns the key shares in an init output file, or the
+// non-empty lines of a plain key file.
+func readUnsealKeys(file string) ([]string, error) {
+	initResponse, content, err := readInitFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if initResponse != nil {
+		if len(initResponse.KeysB64) > 0 {
+			return initResponse.KeysB64, nil
+		}
+		return initResponse.Keys, nil
+	}
+
+	var keys []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no unseal keys found in '%s'", file)
+	}
+	return keys, nil
+}
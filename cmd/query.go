@@ -0,0 +1,107 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// evalQuery evaluates a JSONPath-style query such as "$.data.password",
+// ".metadata.version", "data.tags[0]" or "{.data['api-key']}" against a value
+// made of maps, lists and scalars. The second result is false when the query
+// does not match anything.
+func evalQuery(value interface{}, query string) (interface{}, bool, error) {
+	steps, err := parseQuery(query)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, step := range steps {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			child, ok := current[step]
+			if !ok {
+				return nil, false, nil
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(step)
+			if err != nil {
+				return nil, false, nil
+			}
+			if index < 0 {
+				index += len(current)
+			}
+			if index < 0 || index >= len(current) {
+				return nil, false, nil
+			}
+			value = current[index]
+		default:
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// parseQuery splits a query into map keys and list indexes.
+func parseQuery(query string) ([]string, error) {
+	query = strings.TrimSpace(query)
+	if strings.HasPrefix(query, "{") && strings.HasSuffix(query, "}") {
+		query = strings.TrimSpace(query[1 : len(query)-1])
+	}
+	query = strings.TrimPrefix(query, "$")
+
+	var steps []string
+	for i := 0; i < len(query); {
+		switch query[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ']' in query %q", query)
+			}
+			step := strings.TrimSpace(query[i+1 : i+end])
+			if len(step) >= 2 && (step[0] == '\'' || step[0] == '"') && step[len(step)-1] == step[0] {
+				step = step[1 : len(step)-1]
+			}
+			steps = append(steps, step)
+			i += end + 1
+		default:
+			end := strings.IndexAny(query[i:], ".[")
+			if end < 0 {
+				end = len(query) - i
+			}
+			steps = append(steps, query[i:i+end])
+			i += end
+		}
+	}
+	return steps, nil
+}
+
+// evalTemplate executes a Go template such as "{{.data.username}}" against
+// value. Referencing a missing key is an error.
+func evalTemplate(value interface{}, text string) (string, error) {
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, value); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// fieldText returns a selected value as text: strings as they are and other
+// values as compact JSON.
+func fieldText(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	return compactJSON(value)
+}
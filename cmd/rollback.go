@@ -0,0 +1,108 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackMount   string
+	rollbackPath    string
+	rollbackVersion int
+	rollbackReveal  bool
+	u22             string
+	p22             string
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [mount/path]",
+	Short: "Restore a previous version of a secret as a new version",
+	Long: `
+	Restores a previous version of a secret on a KV version 2 mount by writing its data as a
+	new version, so the history is kept. The version must not be deleted or destroyed. The
+	write uses check-and-set against the current version, so it fails if someone else
+	changes the secret at the same time. The changes applied are printed with the values
+	masked unless --reveal is given.
+
+	Examples of the rollback command(Keycloak Authentication):
+		$ ./cliapp rollback secret/my-secret --version=2
+
+		$ ./cliapp rollback secret/my-secret --version=2 --reveal
+
+	To use Userpass Authentication:
+		$ ./cliapp rollback secret/my-secret --version=2 --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp rollback secret/my-secret --version=2 --user=username --pass=password --instance
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if rollbackVersion < 1 {
+			fmt.Println("Error: Version of secret cannot be less than one")
+			os.Exit(1)
+		}
+
+		login(cmd, u22, p22)
+		kv := resolveKVArgs(args, &rollbackMount, &rollbackPath, true)
+		requireKVv2(kv, "Rollback")
+
+		target, _, err := readVersion(kv, rollbackPath, rollbackVersion)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		current, version, err := readForUpdate(kv, rollbackPath)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if version == rollbackVersion {
+			fmt.Printf("Version %d is already the current version.\n", rollbackVersion)
+			return
+		}
+
+		written, err := auth.Client.KVv2(kv.Path).Put(context.Background(), rollbackPath, target, vault.WithCheckAndSet(version))
+		if isCASError(err) {
+			casConflict(kv, rollbackPath, version, err)
+		}
+		if err != nil {
+			log.Fatalf("unable to write secret: %v", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Rolled back '%s/%s' to version %d as version %d.\n",
+			kv.Path, rollbackPath, rollbackVersion, written.VersionMetadata.Version)
+		printDiff(diffSecrets(current, target), rollbackReveal)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().StringVarP(&rollbackMount, "mount", "m", "", "The mount path of the secret")
+	rollbackCmd.Flags().StringVarP(&rollbackPath, "path", "p", "", "path to the secret")
+
+	// version
+	rollbackCmd.Flags().IntVarP(&rollbackVersion, "version", "v", 0, "version of secret to restore")
+
+	if err := rollbackCmd.MarkFlagRequired("version"); err != nil {
+		fmt.Println(err)
+	}
+
+	rollbackCmd.Flags().BoolVarP(&rollbackReveal, "reveal", "r", false, "Show the values instead of masking them")
+
+	// userpass
+	rollbackCmd.Flags().StringVarP(&u22, "user", "u", "", "Userpass username")
+	rollbackCmd.Flags().StringVarP(&p22, "pass", "a", "", "Userpass password")
+	rollbackCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	rollbackCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
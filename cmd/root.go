@@ -26,11 +26,16 @@ You can use this app to create and manage policies, users, secrets and more.
 There are two ways to authenticate to Vault. You can use the keycloak server or userpass.
 If using userpass, use the commands with the -u and -a flags(see help for more info).
 The default is keycloak, so just enter your username and password when prompted in browser 
+Output of get, list, ls, listUsers, listPolicies and seal-status can be printed as table, json,
+yaml, env or raw with the --format flag, so that scripts can consume it.
 This app is a part of my final year project in University College Dublin.
 	`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		validateFormat()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -48,6 +53,7 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cliapp.yaml)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "table", "Output format: table, json, yaml, env or raw")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,306 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	pathpkg "path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchPath       string
+	searchKey        string
+	searchMetadata   []string
+	searchValueRegex string
+	searchValueHash  string
+	searchShowValues bool
+	searchWorkers    int
+	u28              string
+	p28              string
+)
+
+// searchMatch is a secret, or a key of it, matching the search. The value
+// is only filled in with --show-values.
+type searchMatch struct {
+	Mount string      `json:"mount"`
+	Path  string      `json:"path"`
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <mount/path>...",
+	Short: "Find secrets by path, key name, custom metadata or value",
+	Long: `
+	Walks one or more mounts, or folders of them, and lists the secrets and keys matching all
+	of the given filters. The mounts are walked at the same time and the secrets are read by
+	several workers. The filters are:
+		--path         glob matching the secret name, or the whole path when it contains a slash
+		--key          glob matching key names
+		--metadata     KEY=GLOB matching the custom metadata (KEY alone needs the key to be set),
+		               can be repeated
+		--value-regex  regular expression matching values
+		--value-hash   SHA-256 of a value, in hex, to find where a known credential is stored
+		               without giving it on the command line
+	Only paths and keys are printed; values are only shown with --show-values. Secrets that
+	cannot be read are counted and skipped.
+
+	Examples of the search command(Keycloak Authentication):
+		$ ./cliapp search secret --path="*db*"
+
+		$ ./cliapp search secret kv/team --key="*password*"
+
+		$ ./cliapp search secret --metadata=owner=team-a
+
+		$ ./cliapp search secret kv --value-hash=$(printf %s "$PASSWORD" | sha256sum | cut -d" " -f1)
+
+		$ ./cliapp search secret --value-regex="^AKIA" --show-values --format=json
+
+	To use with Userpass Authentication:
+		$ ./cliapp search secret --key=password --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp search secret --key=password --user=username --pass=password --instance
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if searchPath == "" && searchKey == "" && len(searchMetadata) == 0 && searchValueRegex == "" && searchValueHash == "" {
+			fmt.Println("Error: Give at least one of --path, --key, --metadata, --value-regex or --value-hash")
+			os.Exit(1)
+		}
+		if searchWorkers < 1 {
+			fmt.Println("Error: At least one worker is required")
+			os.Exit(1)
+		}
+		for _, glob := range []string{searchPath, searchKey} {
+			if _, err := pathpkg.Match(glob, ""); err != nil {
+				fmt.Println("Error: Invalid glob:", err)
+				os.Exit(1)
+			}
+		}
+		metadata, err := parseMetadataFilters(searchMetadata)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		var valueRegex *regexp.Regexp
+		if searchValueRegex != "" {
+			if valueRegex, err = regexp.Compile(searchValueRegex); err != nil {
+				fmt.Println("Error: Invalid regular expression:", err)
+				os.Exit(1)
+			}
+		}
+		valueHash := strings.ToLower(strings.TrimPrefix(searchValueHash, "sha256:"))
+		if _, err := hex.DecodeString(valueHash); err != nil || (valueHash != "" && len(valueHash) != sha256.Size*2) {
+			fmt.Println("Error: --value-hash must be a SHA-256 in hex")
+			os.Exit(1)
+		}
+
+		login(cmd, u28, p28)
+		mounts := make([]*kvMount, len(args))
+		roots := make([]string, len(args))
+		for i, arg := range args {
+			if mounts[i], roots[i], err = lookupKVMount(arg); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+
+		// walk the mounts at the same time
+		type candidate struct {
+			kv   *kvMount
+			path string
+		}
+		var (
+			mu         sync.Mutex
+			wg         sync.WaitGroup
+			candidates []candidate
+			seen       = map[string]bool{}
+			walkErr    error
+		)
+		for i := range mounts {
+			wg.Add(1)
+			go func(kv *kvMount, root string) {
+				defer wg.Done()
+				entries, err := walkSecrets(kv, root, 0, searchWorkers)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					walkErr = err
+					return
+				}
+				for _, entry := range entries {
+					if entry.Dir || seen[kv.Path+"/"+entry.Path] { // folders given twice are searched once
+						continue
+					}
+					seen[kv.Path+"/"+entry.Path] = true
+					if searchPath == "" || matchGlob(searchPath, entry.Path) {
+						candidates = append(candidates, candidate{kv: kv, path: entry.Path})
+					}
+				}
+			}(mounts[i], roots[i])
+		}
+		wg.Wait()
+		if walkErr != nil {
+			log.Fatalf("%v", walkErr)
+		}
+
+		readData := searchKey != "" || len(metadata) > 0 || valueRegex != nil || valueHash != ""
+		var matches []searchMatch
+		unreadable := 0
+		jobs := make(chan candidate)
+		for i := 0; i < searchWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					if !readData {
+						mu.Lock()
+						matches = append(matches, searchMatch{Mount: job.kv.Path, Path: job.path})
+						mu.Unlock()
+						continue
+					}
+					found, err := searchSecret(job.kv, job.path, metadata, valueRegex, valueHash)
+					mu.Lock()
+					if err != nil {
+						unreadable++
+					}
+					matches = append(matches, found...)
+					mu.Unlock()
+				}
+			}()
+		}
+		for _, job := range candidates {
+			jobs <- job
+		}
+		close(jobs)
+		wg.Wait()
+
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].Mount != matches[j].Mount {
+				return matches[i].Mount < matches[j].Mount
+			}
+			if matches[i].Path != matches[j].Path {
+				return matches[i].Path < matches[j].Path
+			}
+			return matches[i].Key < matches[j].Key
+		})
+		if unreadable > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %d secrets could not be read and were skipped.\n", unreadable)
+		}
+
+		printFormatted(matches, func() {
+			if len(matches) == 0 {
+				fmt.Println("No matches.")
+				return
+			}
+			for _, match := range matches {
+				line := match.Mount + "/" + match.Path
+				if match.Key != "" {
+					line += ": " + match.Key
+				}
+				if match.Value != nil {
+					line += " = " + fieldText(match.Value)
+				}
+				fmt.Println(line)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVarP(&searchPath, "path", "p", "", "Glob matching the secret name, or the whole path when it contains a slash")
+	searchCmd.Flags().StringVarP(&searchKey, "key", "k", "", "Glob matching key names")
+	searchCmd.Flags().StringArrayVar(&searchMetadata, "metadata", nil, "KEY=GLOB matching the custom metadata, or KEY to need the key, can be repeated")
+	searchCmd.Flags().StringVarP(&searchValueRegex, "value-regex", "r", "", "Regular expression matching values")
+	searchCmd.Flags().StringVar(&searchValueHash, "value-hash", "", "SHA-256 in hex of a value to find")
+	searchCmd.Flags().BoolVar(&searchShowValues, "show-values", false, "Print the values of the matching keys")
+	searchCmd.Flags().IntVarP(&searchWorkers, "workers", "w", 8, "Number of folders listed and secrets read at once")
+
+	// userpass
+	searchCmd.Flags().StringVarP(&u28, "user", "u", "", "Userpass username")
+	searchCmd.Flags().StringVarP(&p28, "pass", "a", "", "Userpass password")
+	searchCmd.MarkFlagsRequiredTogether("user", "pass")
+
+	searchCmd.Flags().BoolVarP(&instance, "instance", "i", false, "Use another Vault instance")
+}
+
+// parseMetadataFilters parses KEY=GLOB filters, where a KEY alone matches
+// any value.
+func parseMetadataFilters(filters []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, filter := range filters {
+		key, glob := filter, "*"
+		if equals := strings.Index(filter, "="); equals >= 0 {
+			key, glob = filter[:equals], filter[equals+1:]
+		}
+		if key == "" {
+			return nil, fmt.Errorf("missing metadata key in '%s'", filter)
+		}
+		if _, err := pathpkg.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob in '%s': %v", filter, err)
+		}
+		parsed[key] = glob
+	}
+	return parsed, nil
+}
+
+// searchSecret reads a secret and returns its keys matching the key and
+// value filters, or the secret itself when only the metadata is filtered.
+func searchSecret(kv *kvMount, secretPath string, metadata map[string]string, valueRegex *regexp.Regexp, valueHash string) ([]searchMatch, error) {
+	secret, err := readSecret(kv, secretPath)
+	if err != nil {
+		return nil, err
+	}
+	for key, glob := range metadata {
+		value, ok := secret.CustomMetadata[key]
+		if !ok {
+			return nil, nil
+		}
+		if matched, _ := pathpkg.Match(glob, fieldText(value)); !matched {
+			return nil, nil
+		}
+	}
+	if searchKey == "" && valueRegex == nil && valueHash == "" {
+		return []searchMatch{{Mount: kv.Path, Path: secretPath}}, nil
+	}
+
+	var matches []searchMatch
+	for key, value := range secret.Data {
+		if searchKey != "" {
+			if matched, _ := pathpkg.Match(searchKey, key); !matched {
+				continue
+			}
+		}
+		text := fieldText(value)
+		if valueRegex != nil && !valueRegex.MatchString(text) {
+			continue
+		}
+		if valueHash != "" {
+			sum := sha256.Sum256([]byte(text))
+			if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(valueHash)) != 1 {
+				continue
+			}
+		}
+		match := searchMatch{Mount: kv.Path, Path: secretPath, Key: key}
+		if searchShowValues {
+			match.Value = value
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
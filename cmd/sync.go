@@ -0,0 +1,32 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	u13 string
+	p13 string
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Synchronize Keycloak configuration into Vault",
+	Long: `
+	Synchronizes configuration kept in Keycloak into Vault, so that it does not have to be
+	duplicated by hand.
+
+	Example of the sync commands(Keycloak Authentication):
+		$ ./cliapp sync groups --adminUsername=admin --adminPassword=password --mapping=group_mapping.json --dry-run
+	`,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	addLoginFlags(syncCmd, &u13, &p13)
+}
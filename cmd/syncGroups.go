@@ -0,0 +1,388 @@
+/*
+Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
+*/
+package cmd
+
+import (
+	"cliapp/auth"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncMount    string
+	syncMapping  string
+	syncDryRun   bool
+	syncPrune    bool
+	syncFullPath bool
+)
+
+// GroupMapping is the mapping file of the sync groups command. It lists the
+// Vault policies to attach to each Keycloak group.
+type GroupMapping struct {
+	Groups          map[string][]string `json:"groups"`
+	DefaultPolicies []string            `json:"default_policies"`
+}
+
+// vaultGroup is the part of a Vault identity group the sync compares.
+type vaultGroup struct {
+	ID            string
+	Name          string
+	Type          string
+	Policies      []string
+	AliasID       string
+	AliasName     string
+	AliasAccessor string
+}
+
+// syncGroupsCmd represents the sync groups command
+var syncGroupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Create Vault identity groups for the Keycloak groups",
+	Long: `
+	Reads the groups of the Keycloak realm and makes sure each has a matching external
+	identity group in Vault, with a group alias on the JWT auth mount so that members get
+	the group's policies when they log in. The policies come from a JSON mapping file:
+
+		{
+		    "groups": {"vault-client": ["user-policy", "default"]},
+		    "default_policies": ["default"]
+		}
+
+	Groups missing from the mapping get the default policies. Every difference between
+	Keycloak and Vault is reported before it is applied; with --dry-run nothing is changed.
+	Vault groups whose alias is on the mount but whose Keycloak group no longer exists are
+	only deleted with --prune. The JWT role must set groups_claim for the aliases to match.
+	With --full-path the Vault groups are named by their Keycloak path with dots instead of
+	slashes (/team/sub becomes team.sub), as Vault group names cannot contain slashes, and
+	the mapping file can use either form. Their aliases are named by the full path, such
+	as /team/sub, so the group membership mapper of the Keycloak client must have "Full
+	group path" turned on for logins to match them.
+
+	Examples of the sync groups command(Keycloak Authentication):
+		$ ./cliapp sync groups --adminUsername=admin --adminPassword=password --mapping=group_mapping.json --dry-run
+
+		$ ./cliapp sync groups --adminUsername=admin --adminPassword=password --mapping=group_mapping.json --prune
+
+		$ ./cliapp sync groups --client-id=cliapp-admin --client-secret-file=admin_client_secret.txt --mapping=group_mapping.json
+
+	To use Userpass Authentication:
+		$ ./cliapp sync groups --adminUsername=admin --adminPassword=password --mapping=group_mapping.json --user=username --pass=password
+
+	To use a different instance:
+		$ ./cliapp sync groups --adminUsername=admin --adminPassword=password --mapping=group_mapping.json --user=username --pass=password --instance
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mapping := GroupMapping{}
+		if syncMapping != "" {
+			content, err := ioutil.ReadFile(syncMapping)
+			if err != nil {
+				log.Fatalf("Error reading mapping file: %v", err)
+			}
+			if err := json.Unmarshal(content, &mapping); err != nil {
+				log.Fatalf("Error unmarshalling mapping file: %v", err)
+			}
+		}
+
+		token, err := getKeycloakAdminToken()
+		if err != nil {
+			log.Fatalf("Error getting Keycloak token: %v", err)
+		}
+
+		keycloakGroups, err := getKeycloakGroups(token)
+		if err != nil {
+			log.Fatalf("Error fetching Keycloak groups: %v", err)
+		}
+
+		login(cmd, u13, p13)
+
+		accessor, err := authMountAccessor(syncMount)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		changes := 0
+		wanted := map[string]bool{}
+		mapped := map[string]bool{}
+		for _, keycloakGroup := range keycloakGroups {
+			name, aliasName := keycloakGroup.Name, keycloakGroup.Name
+			if syncFullPath {
+				// a membership mapper with full paths sends /team/sub
+				aliasName = keycloakGroup.Path
+				name = vaultGroupName(keycloakGroup.Path)
+			}
+			wanted[name] = true
+
+			// the mapping can name the group by its Keycloak path or its Vault name
+			var policies []string
+			ok := false
+			for _, key := range []string{aliasName, strings.TrimPrefix(aliasName, "/"), name} {
+				if policies, ok = mapping.Groups[key]; ok {
+					mapped[key] = true
+					break
+				}
+			}
+			if !ok {
+				policies = mapping.DefaultPolicies
+			}
+			policies = sortedCopy(policies)
+
+			group, err := readVaultGroup(name)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+
+			if group == nil {
+				changes++
+				fmt.Printf("+ create group '%s' with policies %v and alias on %s\n", name, policies, syncMount)
+				if !syncDryRun {
+					id, err := createExternalGroup(name, policies)
+					if err != nil {
+						log.Fatalf("%v", err)
+					}
+					if err := createGroupAlias(aliasName, accessor, id); err != nil {
+						log.Fatalf("%v", err)
+					}
+				}
+				continue
+			}
+
+			if group.Type != "external" {
+				fmt.Printf("! group '%s' exists in Vault as an %s group, skipped\n", name, group.Type)
+				continue
+			}
+
+			if !equalLists(group.Policies, policies) {
+				changes++
+				fmt.Printf("~ update policies of group '%s': %v -> %v\n", name, group.Policies, policies)
+				if !syncDryRun {
+					if err := updateGroupPolicies(group.ID, policies); err != nil {
+						log.Fatalf("%v", err)
+					}
+				}
+			}
+
+			if group.AliasAccessor == "" {
+				changes++
+				fmt.Printf("+ create alias for group '%s' on %s\n", name, syncMount)
+				if !syncDryRun {
+					if err := createGroupAlias(aliasName, accessor, group.ID); err != nil {
+						log.Fatalf("%v", err)
+					}
+				}
+			} else if group.AliasAccessor != accessor {
+				fmt.Printf("! group '%s' already has alias '%s' on another mount (%s), skipped\n", name, group.AliasName, group.AliasAccessor)
+			} else if group.AliasName != aliasName {
+				changes++
+				fmt.Printf("~ rename alias of group '%s' on %s: %s -> %s\n", name, syncMount, group.AliasName, aliasName)
+				if !syncDryRun {
+					if err := updateGroupAlias(group.AliasID, aliasName, accessor, group.ID); err != nil {
+						log.Fatalf("%v", err)
+					}
+				}
+			}
+		}
+
+		for name := range mapping.Groups {
+			if !mapped[name] {
+				fmt.Printf("! mapping for group '%s' has no Keycloak group\n", name)
+			}
+		}
+
+		orphans, err := listMountGroups(accessor)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		for _, group := range orphans {
+			if wanted[group.Name] {
+				continue
+			}
+			if !syncPrune {
+				fmt.Printf("! group '%s' has no Keycloak group, use --prune to delete it\n", group.Name)
+				continue
+			}
+			changes++
+			fmt.Printf("- delete group '%s'\n", group.Name)
+			if !syncDryRun {
+				if _, err := auth.Client.Logical().Delete("identity/group/id/" + group.ID); err != nil {
+					log.Fatalf("unable to delete group '%s': %v", group.Name, err)
+				}
+			}
+		}
+
+		if changes == 0 {
+			fmt.Println("Groups are in sync.")
+		} else if syncDryRun {
+			fmt.Printf("%d changes to apply (dry run).\n", changes)
+		} else {
+			fmt.Printf("%d changes applied.\n", changes)
+		}
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncGroupsCmd)
+
+	// ADMIN parameters
+	addKeycloakAdminFlags(syncGroupsCmd, "", "")
+
+	syncGroupsCmd.Flags().StringVarP(&syncMount, "mount", "m", "jwt", "JWT auth mount the group aliases are created on")
+	syncGroupsCmd.Flags().StringVarP(&syncMapping, "mapping", "f", "", "JSON file mapping Keycloak groups to Vault policies")
+	syncGroupsCmd.Flags().BoolVarP(&syncDryRun, "dry-run", "d", false, "Only report the differences")
+	syncGroupsCmd.Flags().BoolVarP(&syncPrune, "prune", "r", false, "Delete Vault groups whose Keycloak group no longer exists")
+	syncGroupsCmd.Flags().BoolVar(&syncFullPath, "full-path", false, "Name groups by their full Keycloak path, with dots instead of slashes")
+}
+
+// vaultGroupName turns a Keycloak group path into a Vault group name. Vault
+// group names are part of API paths and cannot contain slashes, so the
+// path segments are joined with dots.
+func vaultGroupName(path string) string {
+	return strings.ReplaceAll(strings.Trim(path, "/"), "/", ".")
+}
+
+func authMountAccessor(mount string) (string, error) {
+	enabled, err := auth.Client.Sys().ListAuth()
+	if err != nil {
+		return "", fmt.Errorf("unable to list auth methods: %v", err)
+	}
+	existing, ok := enabled[strings.TrimSuffix(mount, "/")+"/"]
+	if !ok {
+		return "", fmt.Errorf("no auth method enabled at '%s'", mount)
+	}
+	return existing.Accessor, nil
+}
+
+func readVaultGroup(name string) (*vaultGroup, error) {
+	secret, err := auth.Client.Logical().Read("identity/group/name/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read group '%s': %v", name, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+	return toVaultGroup(secret.Data), nil
+}
+
+func toVaultGroup(data map[string]interface{}) *vaultGroup {
+	group := &vaultGroup{
+		ID:       fmt.Sprint(data["id"]),
+		Name:     fmt.Sprint(data["name"]),
+		Type:     fmt.Sprint(data["type"]),
+		Policies: sortedCopy(toStrings(data["policies"])),
+	}
+	if alias, ok := data["alias"].(map[string]interface{}); ok && len(alias) > 0 {
+		group.AliasID = fmt.Sprint(alias["id"])
+		group.AliasName = fmt.Sprint(alias["name"])
+		group.AliasAccessor = fmt.Sprint(alias["mount_accessor"])
+	}
+	return group
+}
+
+// listMountGroups returns the external groups with an alias on the mount.
+func listMountGroups(accessor string) ([]*vaultGroup, error) {
+	secret, err := auth.Client.Logical().List("identity/group/id")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list groups: %v", err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	var groups []*vaultGroup
+	info, _ := secret.Data["key_info"].(map[string]interface{})
+	for _, id := range toStrings(secret.Data["keys"]) {
+		if entry, ok := info[id].(map[string]interface{}); ok {
+			entry["id"] = id
+			group := toVaultGroup(entry)
+			if group.AliasAccessor == accessor {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups, nil
+}
+
+func createExternalGroup(name string, policies []string) (string, error) {
+	secret, err := auth.Client.Logical().Write("identity/group", map[string]interface{}{
+		"name":     name,
+		"type":     "external",
+		"policies": policies,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create group '%s': %v", name, err)
+	}
+	if secret == nil || secret.Data["id"] == nil {
+		return "", fmt.Errorf("no id returned for group '%s'", name)
+	}
+	return fmt.Sprint(secret.Data["id"]), nil
+}
+
+func updateGroupPolicies(id string, policies []string) error {
+	_, err := auth.Client.Logical().Write("identity/group/id/"+id, map[string]interface{}{
+		"policies": policies,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to update group policies: %v", err)
+	}
+	return nil
+}
+
+func createGroupAlias(name, accessor, groupID string) error {
+	_, err := auth.Client.Logical().Write("identity/group-alias", map[string]interface{}{
+		"name":           name,
+		"mount_accessor": accessor,
+		"canonical_id":   groupID,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create alias for group '%s': %v", name, err)
+	}
+	return nil
+}
+
+func updateGroupAlias(id, name, accessor, groupID string) error {
+	_, err := auth.Client.Logical().Write("identity/group-alias/id/"+id, map[string]interface{}{
+		"name":           name,
+		"mount_accessor": accessor,
+		"canonical_id":   groupID,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to rename group alias to '%s': %v", name, err)
+	}
+	return nil
+}
+
+func toStrings(value interface{}) []string {
+	var list []string
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			list = append(list, fmt.Sprint(item))
+		}
+	}
+	return list
+}
+
+func sortedCopy(list []string) []string {
+	sorted := append([]string{}, list...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func equalLists(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -28,7 +28,7 @@ var delVersions []int
 
 // undeleteCmd represents the undelete command
 var undeleteCmd = &cobra.Command{
-	Use:   "undelete",
+	Use:   "undelete [mount/path]",
 	Short: "Undelets the data for the provided version",
 	Long: `
 	Undeletes the data for the provided version and path in the key-value store.
@@ -36,16 +36,20 @@ var undeleteCmd = &cobra.Command{
 	be provided or multiple versions.
 
 	Examples of the undelete command(Keycloak Authentication):
-		$ ./cliapp undelete --mount=secret --path=secret/my-secret --version=2
+		$ ./cliapp undelete secret/my-secret --version=2
 
-		$ ./cliapp undelete --mount=secret --path=secret/my-secret --version=2,3,4
+		$ ./cliapp undelete secret/my-secret --version=2,3,4
+
+	The mount is found from the mount table. The mount and the path inside it can also be given with flags:
+		$ ./cliapp undelete --mount=secret --path=my-secret --version=2
 
 	To use Userpass Authentication:
-		$ ./cliapp undelete --mount=secret --path=secret/my-secret --version=2 --user=username --pass=password
+		$ ./cliapp undelete secret/my-secret --version=2 --user=username --pass=password
 
 	To use a different instance:
-		$ ./cliapp undelete --mount=secret --path=secret/my-secret --version=2 --user=username --pass=password --instance
+		$ ./cliapp undelete secret/my-secret --version=2 --user=username --pass=password --instance
 	`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
 			address := util.UpdateAddress(instance)
@@ -59,8 +63,8 @@ var undeleteCmd = &cobra.Command{
 			auth.KeycloakAuth(address)
 		}
 
-		util.ValidatePath(undelMount)
-		util.ValidatePath(undelPath)
+		kv := resolveKVArgs(args, &undelMount, &undelPath, true)
+		requireKVv2(kv, "undelete")
 
 		if strings.Contains(undelVersion, ",") { // multiple versions
 			vers := strings.Split(undelVersion, ",")
@@ -101,17 +105,9 @@ func init() {
 	//mount
 	undeleteCmd.Flags().StringVarP(&undelMount, "mount", "m", "", "The mount path to undelete secrets")
 
-	if err := undeleteCmd.MarkFlagRequired("mount"); err != nil {
-		fmt.Println(err)
-	}
-
 	// path
 	undeleteCmd.Flags().StringVarP(&undelPath, "path", "p", "", "path to the secret")
 
-	if err := undeleteCmd.MarkFlagRequired("path"); err != nil {
-		fmt.Println(err)
-	}
-
 	// version
 	undeleteCmd.Flags().StringVarP(&undelVersion, "version", "v", "", "version of secret")
 
@@ -6,7 +6,6 @@ package cmd
 import (
 	"cliapp/auth"
 	"cliapp/util"
-	"context"
 	"fmt"
 	"log"
 	"os"
@@ -15,32 +14,86 @@ import (
 )
 
 var (
-	upMountPath string
-	upPath      string
-	upKey       string
-	upValue     string
-	u3          string
-	p3          string
+	upMountPath   string
+	upPath        string
+	upKey         string
+	upValue       string
+	upMergePatch  string
+	upCAS         int
+	upInteractive bool
+	upRetries     int
+	u3            string
+	p3            string
 )
 
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
-	Use:   "update",
+	Use:   "update [mount/path] [key=value | key:=json]...",
 	Short: "Updates the data to the corresponding path in the key-value store",
 	Long: `
 	Updates the data to the corresponding path in the key-value store.
 	A mount, path and a key value pair is required to update the data.
 	
 	Examples of the update command(Keycloak Authentication):
-		$ ./cliapp update --mount=secret --path=secret/my-secret --key=val --value=foo
+		$ ./cliapp update secret/my-secret --key=val --value=foo
+
+	Several keys can be updated at once with key=value arguments for strings and key:=value
+	arguments for JSON values such as numbers, booleans, arrays and objects. The update is a
+	JSON merge patch: nested objects are merged key by key and a null value removes the key.
+	A merge patch document can also be read from a JSON file, or from stdin with "-".
+		$ ./cliapp update secret/my-secret user=admin port:=8080 old_key:=null
+
+		$ ./cliapp update secret/my-secret 'db:={"port":5433}'
+
+		$ ./cliapp update secret/my-secret --merge-patch=patch.json
+
+	The mount is found from the mount table. The mount and the path inside it can also be given with flags:
+		$ ./cliapp update --mount=secret --path=my-secret --key=val --value=foo
+
+	To protect against overwriting someone else's change, give the version the secret is
+	expected to be at with --cas. With --interactive the current version is read and used
+	for check-and-set, and on a conflict you are asked whether to merge your key into the
+	new version, overwrite it or abort.
+		$ ./cliapp update secret/my-secret --key=val --value=foo --cas=3
+
+		$ ./cliapp update secret/my-secret --key=val --value=foo --interactive
 
 	To use with Userpass Authentication:
-		$ ./cliapp update --mount=secret --path=secret/my-secret --key=val --value=foo --user=user --pass=pass
+		$ ./cliapp update secret/my-secret --key=val --value=foo --user=user --pass=pass
 
 	To use a different instance:
-		$ ./cliapp update --mount=secret --path=secret/my-secret --key=val --value=foo --user=user --pass=pass --instance
+		$ ./cliapp update secret/my-secret --key=val --value=foo --user=user --pass=pass --instance
 		`,
+	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		target, pairs, files, err := parseKVArgs(args)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		UpsecretData := make(map[string]interface{})
+		if upMergePatch != "" {
+			UpsecretData, err = readMergePatch(upMergePatch)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		if upKey != "" || upValue != "" {
+			util.ValidateKVsecret(upKey, upValue)
+			UpsecretData[upKey] = upValue
+		}
+		for key, value := range pairs {
+			UpsecretData[key] = value
+		}
+		if len(UpsecretData) == 0 {
+			fmt.Println("Error: A key and value are required to update a KV secret")
+			os.Exit(1)
+		}
+
+		checkSecretSize(UpsecretData)
+
 		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
 			address := util.UpdateAddress(instance)
 			auth.AuthenticateWithUserPass(u3, p3, address)
@@ -53,17 +106,34 @@ var updateCmd = &cobra.Command{
 			auth.KeycloakAuth(address)
 		}
 
-		util.ValidatePath(upPath)
-		util.ValidatePath(upMountPath)
+		kv := resolveKVArgs(target, &upMountPath, &upPath, true)
+		files = requireFileSupport(kv, upPath, files)
 
-		UpsecretData := make(map[string]interface{})
-		UpsecretData[upKey] = upValue
+		if upInteractive {
+			requireKVv2(kv, "Check-and-set")
+			if _, err := readModifyWrite(kv, upPath, UpsecretData, false, upRetries); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			saveFiles(kv, upPath, files)
+			fmt.Println("Secret updated successfully.")
+			return
+		}
 
-		_, err := auth.Client.KVv2(upMountPath).Patch(context.Background(), upPath, UpsecretData)
+		cas := -1
+		if cmd.Flags().Changed("cas") {
+			requireKVv2(kv, "Check-and-set")
+			cas = upCAS
+		}
+		err = patchSecret(kv, upPath, UpsecretData, casOptions(cas)...)
+		if isCASError(err) {
+			casConflict(kv, upPath, cas, err)
+		}
 		if err != nil {
 			log.Fatalf("%v", err)
 		}
 
+		saveFiles(kv, upPath, files)
 		fmt.Println("Secret updated successfully.")
 	},
 }
@@ -74,30 +144,23 @@ func init() {
 	//mount
 	updateCmd.Flags().StringVarP(&upMountPath, "mount", "m", "", "The mount path to update secret")
 
-	if err := updateCmd.MarkFlagRequired("mount"); err != nil {
-		fmt.Println(err)
-	}
-
 	//path
 	updateCmd.Flags().StringVarP(&upPath, "path", "p", "", "path to the secret")
 
-	if err := updateCmd.MarkFlagRequired("path"); err != nil {
-		fmt.Println(err)
-	}
-
 	//key
 	updateCmd.Flags().StringVarP(&upKey, "key", "k", "", "key of the secret")
 
-	if err := updateCmd.MarkFlagRequired("key"); err != nil {
-		fmt.Println(err)
-	}
-
 	//value
 	updateCmd.Flags().StringVarP(&upValue, "value", "v", "", "value of the secret")
 
-	if err := updateCmd.MarkFlagRequired("value"); err != nil {
-		fmt.Println(err)
-	}
+	// merge patch
+	updateCmd.Flags().StringVarP(&upMergePatch, "merge-patch", "f", "", "JSON merge patch file to apply, - for stdin")
+
+	// check-and-set
+	updateCmd.Flags().IntVar(&upCAS, "cas", 0, "Only update if the secret is at this version")
+	updateCmd.Flags().BoolVar(&upInteractive, "interactive", false, "Update with check-and-set against the version read and prompt on conflicts")
+	updateCmd.Flags().IntVar(&upRetries, "retries", 3, "Number of conflicts to resolve with --interactive before giving up")
+	updateCmd.MarkFlagsMutuallyExclusive("cas", "interactive")
 
 	// userpass
 	updateCmd.Flags().StringVarP(&u3, "user", "u", "", "Userpass username")
@@ -4,13 +4,9 @@ Copyright © 2023 Dawid Skraba <dawid.skraba@ucdconnect.ie>
 package cmd
 
 import (
-	"bufio"
 	"cliapp/auth"
 	"cliapp/util"
-	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"strings"
@@ -19,126 +15,149 @@ import (
 )
 
 var (
-	mountPath string
-	path      string
-	key       string
-	value     string
-	u2        string
-	p2        string
+	mountPath        string
+	path             string
+	key              string
+	value            string
+	writeFromFiles   []string
+	writeCAS         int
+	writeInteractive bool
+	writeRetries     int
+	u2               string
+	p2               string
 )
 
-var keys []string
-var values []string
-var Stdininput string
-
 // writeCmd represents the write command
 var writeCmd = &cobra.Command{
-	Use:   "write",
+	Use:   "write [mount/path] [key=value | key:=json]...",
 	Short: "Writes data to the vault at the path",
 	Long: `	
-	This data should be specified as a key=value pair. The input could also be specified
-	from a file, when using the @ symbol at the start of the value variable, this file being made up of multiple key value pairs. 
-	Multiple key value pairs are also permitted. You can write from stdin also, when specified the value with the '-' sign. The mount 
+	Writes the data of a secret as a new version, replacing all of its keys. The keys are
+	given as arguments after the path: key=value stores the value as a string, while
+	key:=value parses it as JSON, so that numbers, booleans, arrays and nested objects keep
+	their type. A value of @file stores the contents of the file and a value of - reads all
+	of stdin, including multiple lines (without the final newline). Whole documents can be
+	written with --from-file, a JSON or YAML object of keys, or - for stdin. Binary files
+	are stored base64 encoded; the encoding and permissions of file values are recorded in
+	the custom metadata so that get --field --output restores the file. Recording them
+	needs the patch capability on the metadata of the secret, which the admin policy
+	grants: without it text files are written without their permissions and binary files
+	are refused. A warning is shown
+	for secrets over 512 KiB, as Vault's storage rejects entries over 1 MiB by default. The mount
 	should be specified with a already running engine at that path(check documentation
 	for full details) and this is where the secrets will be mounted.
 
 	Examples of the write command(Keycloak Authentication):
-		$ ./cliapp write --mount=secret --path=secret/my-secret --key=customer_name --value=Apple_Inc.
-		
-		$ ./cliapp write --mount=secret --path=secret/my-secret --key=key1,key2 --value=val1,val2
+		$ ./cliapp write secret/my-secret customer_name=Apple_Inc.
+
+		$ ./cliapp write secret/my-secret key1=val1 key2="value, with a comma"
+
+		$ ./cliapp write secret/my-secret user=admin port:=8080 debug:=false 'tags:=["a","b"]'
+
+		$ ./cliapp write secret/my-secret certificate=@cert.pem
+
+		$ cat notes.txt | ./cliapp write secret/my-secret notes=-
+
+		$ ./cliapp write secret/my-secret --from-file=secret.yaml
+
+	The mount is found from the mount table. The mount and the path inside it can also be given with flags:
+		$ ./cliapp write --mount=secret --path=my-secret customer_name=Apple_Inc.
+
+	To protect against overwriting someone else's change, give the version the secret is
+	expected to be at with --cas (0 to only create the secret). With --interactive the
+	current version is read and used for check-and-set, and on a conflict you are asked
+	whether to merge your keys into the new version, overwrite it or abort.
+		$ ./cliapp write secret/my-secret customer_name=Apple_Inc. --cas=3
 
-		$ ./cliapp write --mount=secret --path=secret/my-secret --key=key --value=-
+		$ ./cliapp write secret/my-secret customer_name=Apple_Inc. --interactive
 
-		$ ./cliapp write --mount=secret --path=secret/my-secret --key=@file.json --value=file
-	
 	To use with Userpass Authentication:
-		$ ./cliapp write --mount=secret --path=secret/my-secret --key=customer_name --value=Apple_Inc. --user=username --pass=password
+		$ ./cliapp write secret/my-secret customer_name=Apple_Inc. --user=username --pass=password
 
 	To use a different instance:
-		$ ./cliapp write --mount=secret --path=secret/my-secret --key=customer_name --value=Apple_Inc. --user=username --pass=password --instance
+		$ ./cliapp write secret/my-secret customer_name=Apple_Inc. --user=username --pass=password --instance
 	`,
+	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
-			address := util.UpdateAddress(instance)
-			auth.AuthenticateWithUserPass(u2, p2, address)
-		} else {
-			if cmd.Flag("instance").Changed {
-				fmt.Println("Error: You must provide a username and password to use a different instance.")
+		if key != "" || value != "" { // single pair from the old flags
+			if strings.HasPrefix(key, "@") {
+				fmt.Println("Error: Write a JSON file with --from-file=" + key[1:])
 				os.Exit(1)
 			}
-			address := util.UpdateAddress(false)
-			auth.KeycloakAuth(address)
-		}
-
-		if strings.Contains(key, ",") || strings.Contains(value, ",") { // multiple key value pairs
-			key := strings.Split(key, ",")
-			value := strings.Split(value, ",")
-			for _, kesy := range key {
-				keys = append(keys, kesy)
-			}
-			for _, vasl := range value {
-				values = append(values, vasl)
+			if key == "" || value == "" || strings.Contains(key, ",") {
+				fmt.Println("Error: --key and --value take a single key and value, give several keys as key=value arguments")
+				os.Exit(1)
 			}
-		} else {
-			keys = append(keys, key)
-			values = append(values, value)
+			args = append(args, key+"="+value)
 		}
 
-		if len(keys) != len(values) && keys[0] != "@" { // check if all keys have corresponding values
-			fmt.Println("Error: All keys must have corresponding values")
+		target, pairs, files, err := parseKVArgs(args)
+		if err != nil {
+			fmt.Println("Error:", err)
 			os.Exit(1)
 		}
 
-		if strings.Contains(key, "@") { // JSON file as input
-			file := strings.Split(key, "@")
-			var nfile = file[1]
-			jsonBytes, err := ioutil.ReadFile(nfile)
+		secretData := make(map[string]interface{})
+		for _, file := range writeFromFiles {
+			data, err := readDataFile(file)
 			if err != nil {
-				fmt.Println("Error reading JSON file:", err)
-				return
+				fmt.Println("Error:", err)
+				os.Exit(1)
 			}
-
-			var data map[string]string
-			err = json.Unmarshal(jsonBytes, &data)
-			if err != nil {
-				fmt.Println("Error unmarshalling JSON data:", err)
-				return
+			for key, value := range data {
+				secretData[key] = value
 			}
-			keys = []string{}
-			values = []string{}
-			for k, v := range data {
-				keys = append(keys, k)
-				values = append(values, v)
+		}
+		for key, value := range pairs {
+			secretData[key] = value
+		}
+		if len(secretData) == 0 {
+			fmt.Println("Error: At least one key=value argument or --from-file is required to write a KV secret")
+			os.Exit(1)
+		}
+
+		checkSecretSize(secretData)
+
+		if cmd.Flag("user").Changed && cmd.Flag("pass").Changed {
+			address := util.UpdateAddress(instance)
+			auth.AuthenticateWithUserPass(u2, p2, address)
+		} else {
+			if cmd.Flag("instance").Changed {
+				fmt.Println("Error: You must provide a username and password to use a different instance.")
+				os.Exit(1)
 			}
+			address := util.UpdateAddress(false)
+			auth.KeycloakAuth(address)
 		}
 
-		for i, s := range values { // STDIN
-			if s == "-" {
-				scanner := bufio.NewScanner(os.Stdin)
-				if scanner.Scan() {
-					Stdininput = scanner.Text()
-				}
-				values[i] = Stdininput
+		kv := resolveKVArgs(target, &mountPath, &path, true)
+		files = requireFileSupport(kv, path, files)
+
+		if writeInteractive {
+			requireKVv2(kv, "Check-and-set")
+			if _, err := readModifyWrite(kv, path, secretData, true, writeRetries); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
 			}
+			saveFiles(kv, path, files)
+			fmt.Println("Secret written successfully.")
+			return
 		}
 
-		for i := 0; i < len(keys) && i < len(values); i++ {
-			util.ValidateKVsecret(keys[i], values[i])
+		cas := -1
+		if cmd.Flags().Changed("cas") {
+			requireKVv2(kv, "Check-and-set")
+			cas = writeCAS
 		}
-		util.ValidatePath(path)
-		util.ValidatePath(mountPath)
-		secretData := make(map[string]interface{})
-		for i := 0; i < len(keys) && i < len(values); i++ {
-			key := keys[i]
-			value := values[i]
-			secretData[key] = value
+		err = writeSecret(kv, path, secretData, casOptions(cas)...)
+		if isCASError(err) {
+			casConflict(kv, path, cas, err)
 		}
-
-		_, err := auth.Client.KVv2(mountPath).Put(context.Background(), path, secretData)
 		if err != nil {
 			log.Fatalf("%v", err)
 		}
 
+		saveFiles(kv, path, files)
 		fmt.Println("Secret written successfully.")
 	},
 }
@@ -149,31 +168,30 @@ func init() {
 	//mount
 	writeCmd.Flags().StringVarP(&mountPath, "mount", "m", "", "The mount path to write secrets to")
 
-	if err := writeCmd.MarkFlagRequired("mount"); err != nil {
-		fmt.Println(err)
-	}
-
 	//path
 	writeCmd.Flags().StringVarP(&path, "path", "p", "", "path to the secret")
 
-	if err := writeCmd.MarkFlagRequired("path"); err != nil {
-		fmt.Println(err)
-	}
-
 	//key
 	writeCmd.Flags().StringVarP(&key, "key", "k", "", "key of the secret")
-
-	if err := writeCmd.MarkFlagRequired("key"); err != nil {
+	if err := writeCmd.Flags().MarkDeprecated("key", "give the keys as key=value arguments"); err != nil {
 		fmt.Println(err)
 	}
 
 	//value
 	writeCmd.Flags().StringVarP(&value, "value", "v", "", "value of the secret")
-
-	if err := writeCmd.MarkFlagRequired("value"); err != nil {
+	if err := writeCmd.Flags().MarkDeprecated("value", "give the keys as key=value arguments"); err != nil {
 		fmt.Println(err)
 	}
 
+	// documents
+	writeCmd.Flags().StringArrayVarP(&writeFromFiles, "from-file", "f", nil, "JSON or YAML file with the keys of the secret, - for stdin, can be repeated")
+
+	// check-and-set
+	writeCmd.Flags().IntVar(&writeCAS, "cas", 0, "Only write if the secret is at this version, 0 to only create it")
+	writeCmd.Flags().BoolVar(&writeInteractive, "interactive", false, "Write with check-and-set against the version read and prompt on conflicts")
+	writeCmd.Flags().IntVar(&writeRetries, "retries", 3, "Number of conflicts to resolve with --interactive before giving up")
+	writeCmd.MarkFlagsMutuallyExclusive("cas", "interactive")
+
 	// userpass
 	writeCmd.Flags().StringVarP(&u2, "user", "u", "", "Userpass username")
 	writeCmd.Flags().StringVarP(&p2, "pass", "a", "", "Userpass password")
@@ -15,7 +15,7 @@ sleep 10
 
 export VAULT_ADDR="http://127.0.0.1:8200"
 
-INIT_OUTPUT=$(curl --request PUT --data '{"secret_shares": 5, "secret_threshold": 3}' ${VAULT_ADDR}/v1/sys/init)
+./cliapp operator init --shares=5 --threshold=3 --output=vault-init.json
 INIT_EXIT_CODE=$?
 
 if [ $INIT_EXIT_CODE -ne 0 ]; then
@@ -23,53 +23,32 @@ if [ $INIT_EXIT_CODE -ne 0 ]; then
   exit 1
 fi
 
-UNSEAL_KEYS=$(echo "$INIT_OUTPUT" | jq -r '.keys[]')
-ROOT_TOKEN=$(echo "$INIT_OUTPUT" | jq -r '.root_token')
+ROOT_TOKEN=$(jq -r '.root_token' vault-init.json)
 
-echo "Initial Root Token: $ROOT_TOKEN"
-
-KEY_COUNT=0
-for KEY in $UNSEAL_KEYS; do
-  vault operator unseal "$KEY"
-  KEY_COUNT=$((KEY_COUNT + 1))
-  if [ $KEY_COUNT -ge 3 ]; then
-    break
-  fi
-done
+./cliapp operator unseal --key-file=vault-init.json
 
 export VAULT_TOKEN="$ROOT_TOKEN"
 
-echo "Vault server is now unsealed and ready to use."
 echo "Waiting for raft storage to finish initialization..."
 sleep 10
 
-# write the admin and user policies
-
-vault policy write admin-policy policies/admin-policy.hcl
-vault policy write user-policy policies/user-policy.hcl
-
-# enable the userpass auth method
+# write the admin and user policies, enable the userpass and jwt auth methods
 
-vault auth enable userpass
-vault write auth/userpass/users/admin password=admin policies=admin-policy
-vault write auth/userpass/users/user password=pass policies=user-policy
+./cliapp operator bootstrap --token-file=vault-init.json --create-user=admin:admin:admin-policy --create-user=user:pass:user-policy
 
 # enable the kv secrets engine at the 'kv' path
 
 vault secrets enable -version=2 kv
 
-# setup oidc method
-CLIENT_SECRET=$(sed 's/^[[:space:]]*//;s/[[:space:]]*$//' client_secret.txt)
+# setup oidc method (in ui, token type is not set to service and no TTLs)
 
-vault auth enable oidc # in ui, token type is not set to service and no TTLs
+./cliapp oidc setup --type=oidc --mount=oidc --client-secret-file=client_secret.txt --default-role=default --user=admin --pass=admin
 
-vault write auth/oidc/role/default \
-    allowed_redirect_uris="http://localhost:8250/oidc/callback,http://localhost:8200/ui/vault/auth/oidc/oidc/callback" \
-    user_claim="sub" \
-    groups_claim="groups" \
-    policies="default"
-
-vault write auth/oidc/config oidc_discovery_url=http://localhost:8080/auth/realms/my_realm oidc_client_id=vault-client oidc_client_secret="$CLIENT_SECRET" default_role=default 
+./cliapp oidc role write --mount=oidc --name=default \
+    --allowed-redirect-uris="http://localhost:8250/oidc/callback,http://localhost:8200/ui/vault/auth/oidc/oidc/callback" \
+    --groups-claim=groups \
+    --policies=default \
+    --user=admin --pass=admin
 
 GROUP_ID=$(vault write -format=json identity/group @group_payload.json | jq -r '.data.id')
 
@@ -87,16 +66,12 @@ vault write -format=json identity/group-alias @group_alias_payload.json
 
 # jwt auth method
 
-vault auth enable jwt
-
-vault write auth/jwt/role/user-policy role_type=jwt \
-  bound_audiences="vault-client" \
-  user_claim="sub" \
-  policies="user-policy" \
-  ttl=1h \
-  max_ttl=24h
-
-vault write auth/jwt/config \
-    oidc_discovery_url="http://localhost:8080/auth/realms/my_realm" \
-    default_role="user-policy"
+./cliapp oidc setup --default-role=user-policy --user=admin --pass=admin
 
+./cliapp oidc role write --name=user-policy \
+  --bound-audiences=vault-client \
+  --groups-claim=groups \
+  --policies=user-policy \
+  --ttl=1h \
+  --max-ttl=24h \
+  --user=admin --pass=admin
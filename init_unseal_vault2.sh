@@ -2,7 +2,7 @@
 
 export VAULT_ADDR="http://localhost:8400"
 
-INIT_OUTPUT=$(curl --request PUT --data '{"secret_shares": 5, "secret_threshold": 3}' ${VAULT_ADDR}/v1/sys/init)
+./cliapp operator init --shares=5 --threshold=3 --output=vault-init2.json --instance
 INIT_EXIT_CODE=$?
 
 if [ $INIT_EXIT_CODE -ne 0 ]; then
@@ -10,34 +10,15 @@ if [ $INIT_EXIT_CODE -ne 0 ]; then
   exit 1
 fi
 
-UNSEAL_KEYS=$(echo "$INIT_OUTPUT" | jq -r '.keys[]')
-ROOT_TOKEN=$(echo "$INIT_OUTPUT" | jq -r '.root_token')
+ROOT_TOKEN=$(jq -r '.root_token' vault-init2.json)
 
-echo "Initial Root Token: $ROOT_TOKEN"
-
-KEY_COUNT=0
-for KEY in $UNSEAL_KEYS; do
-  vault operator unseal "$KEY"
-  KEY_COUNT=$((KEY_COUNT + 1))
-  if [ $KEY_COUNT -ge 3 ]; then
-    break
-  fi
-done
+./cliapp operator unseal --key-file=vault-init2.json --instance
 
 export VAULT_TOKEN="$ROOT_TOKEN"
 
-echo "Vault server is now unsealed and ready to use."
-
-# write the admin and user policies
-
-vault policy write admin-policy policies/admin-policy.hcl
-vault policy write user-policy policies/user-policy.hcl
-
-# enable the userpass auth method
+# write the admin and user policies, enable the userpass and jwt auth methods
 
-vault auth enable userpass
-vault write auth/userpass/users/admin password=admin policies=admin-policy
-vault write auth/userpass/users/user password=pass policies=user-policy
+./cliapp operator bootstrap --token-file=vault-init2.json --create-user=admin:admin:admin-policy --create-user=user:pass:user-policy --instance
 
 # enable the kv secrets engine at the 'kv' path
 
@@ -135,3 +135,43 @@ curl -X POST "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients/${CLIENT_I
         }"
 
 
+
+# create a confidential client with a service account for cliapp's admin commands
+ADMIN_CLIENT_NAME="cliapp-admin"
+echo "Creating admin service account client..."
+curl -X POST "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" \
+    -H "Content-Type: application/json" \
+    -d "{
+          \"clientId\": \"${ADMIN_CLIENT_NAME}\",
+          \"enabled\": true,
+          \"publicClient\": false,
+          \"serviceAccountsEnabled\": true,
+          \"standardFlowEnabled\": false,
+          \"directAccessGrantsEnabled\": false
+        }"
+
+ADMIN_CLIENT_ID=$(curl -X GET "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" \
+    -H "Content-Type: application/json" | jq -r --arg CLIENT_NAME "$ADMIN_CLIENT_NAME" '.[] | select(.clientId==$CLIENT_NAME) | .id')
+
+curl -X POST "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients/${ADMIN_CLIENT_ID}/client-secret" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" \
+    -H "Content-Type: application/json" | jq -r '.value' > admin_client_secret.txt
+
+SERVICE_ACCOUNT_ID=$(curl -X GET "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients/${ADMIN_CLIENT_ID}/service-account-user" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" | jq -r '.id')
+
+REALM_MANAGEMENT_ID=$(curl -X GET "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" \
+    -H "Content-Type: application/json" | jq -r '.[] | select(.clientId=="realm-management") | .id')
+
+ADMIN_ROLES=$(curl -X GET "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/clients/${REALM_MANAGEMENT_ID}/roles" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" | jq -c '[.[] | select(.name=="manage-users" or .name=="view-users" or .name=="query-groups" or .name=="view-realm" or .name=="manage-realm" or .name=="view-events" or .name=="manage-clients" or .name=="view-clients")]')
+
+curl -X POST "${KEYCLOAK_BASE_URL}/admin/realms/${REALM_NAME}/users/${SERVICE_ACCOUNT_ID}/role-mappings/clients/${REALM_MANAGEMENT_ID}" \
+    -H "Authorization: Bearer ${ACCESS_TOKEN}" \
+    -H "Content-Type: application/json" \
+    -d "${ADMIN_ROLES}"
+
+echo "Client '${ADMIN_CLIENT_NAME}' secret saved in admin_client_secret.txt"
@@ -42,6 +42,12 @@ path "sys/auth"
   capabilities = ["read"]
 }
 
+# Manage identity entities, groups and aliases (identity and sync groups commands)
+path "identity/*"
+{
+  capabilities = ["create", "read", "update", "delete", "list"]
+}
+
 # Enable and manage the key/value secrets engine at `secret/` path
 
 # List, create, update, and delete key/value secrets
@@ -73,7 +79,7 @@ path "test/destroy/*" {
 }
 
 path "test/metadata/*" {
-  capabilities = ["list", "read", "delete"]
+  capabilities = ["create", "update", "patch", "list", "read", "delete"]
 }
 
 path "test/undelete/*" {
@@ -90,7 +96,7 @@ path "kv/destroy/*" {
 }
 
 path "kv/metadata/*" {
-  capabilities = ["list", "read", "delete"]
+  capabilities = ["create", "update", "patch", "list", "read", "delete"]
 }
 
 path "kv/undelete/*" {
@@ -16,6 +16,9 @@ sleep 10
 echo "Initializing Keycloak..."
 source ./keycloak_init.sh
 
+echo "Building cliapp..."
+go build -o cliapp .
+
 echo "Initializing and unsealing Vault..."
 ./init_unseal_vault.sh
 
@@ -26,6 +26,9 @@ echo "Waiting for Keycloak to become available..."
 echo "Initializing Keycloak..."
 source ./keycloak_init.sh
 
+echo "Building cliapp..."
+go build -o cliapp .
+
 echo "Initializing and unsealing Vault..."
 ./init_unseal_vault.sh
 